@@ -0,0 +1,222 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// isArchive reports whether a path is an archive fixfiles can collect from
+func isArchive(filePath string) bool {
+	lower := strings.ToLower(filePath)
+	return strings.HasSuffix(lower, ".zip") || strings.HasSuffix(lower, ".tar.gz") || strings.HasSuffix(lower, ".tgz")
+}
+
+// MountArchive makes the contents of an archive readable at its own path, so its files are shown
+// inside it, and returns the absolute path of the entry file and its project root. The project
+// root is looked for inside the archive only.
+func MountArchive(archivePath string, entry string) (string, string, error) {
+	archive, err := openArchive(archivePath)
+	if err != nil {
+		return "", "", err
+	}
+	name, err := archiveEntry(archive, entry)
+	if err != nil {
+		return "", "", err
+	}
+	fileSystem = newMountFS(archivePath, archive, fileSystem)
+	entryPath := filepath.Join(archivePath, filepath.FromSlash(name))
+
+	projectRoot, err := FindProjectRoot(entryPath)
+	if rel, relErr := filepath.Rel(archivePath, projectRoot); err != nil || relErr != nil || strings.HasPrefix(rel, "..") {
+		projectRoot = archivePath
+		if top := archiveTopDir(archive); top != "" && strings.HasPrefix(name, top+"/") {
+			projectRoot = filepath.Join(archivePath, top)
+		}
+	}
+	return entryPath, projectRoot, nil
+}
+
+// openArchive opens a zip or gzipped tar archive as a file system, without extracting it
+func openArchive(archivePath string) (fs.FS, error) {
+	if strings.HasSuffix(strings.ToLower(archivePath), ".zip") {
+		reader, err := zip.OpenReader(archivePath)
+		if err != nil {
+			return nil, fmt.Errorf("could not open zip %s: %v", archivePath, err)
+		}
+		return reader, nil
+	}
+
+	file, err := os.Open(archivePath)
+	if err != nil {
+		return nil, err
+	}
+	defer file.Close()
+
+	gz, err := gzip.NewReader(file)
+	if err != nil {
+		return nil, fmt.Errorf("could not open %s: %v", archivePath, err)
+	}
+	defer gz.Close()
+
+	archive, err := readTar(tar.NewReader(gz))
+	if err != nil {
+		return nil, fmt.Errorf("could not read %s: %v", archivePath, err)
+	}
+	return archive, nil
+}
+
+// tarFS is the contents of a tar archive, held in memory
+type tarFS struct {
+	entries  map[string]*memFileInfo
+	children map[string][]string
+	contents map[string][]byte
+}
+
+// readTar reads the regular files and directories of a tar archive. Links to files in the
+// archive are read as copies of them; anything else, like devices, is skipped.
+func readTar(reader *tar.Reader) (*tarFS, error) {
+	archive := &tarFS{
+		entries:  map[string]*memFileInfo{".": {name: ".", mode: fs.ModeDir | 0755}},
+		children: make(map[string][]string),
+		contents: make(map[string][]byte),
+	}
+
+	links := make(map[string]string)
+	for {
+		header, err := reader.Next()
+		if err == io.EOF {
+			break
+		} else if err != nil {
+			return nil, err
+		}
+
+		name := path.Clean(strings.TrimPrefix(header.Name, "/"))
+		if !fs.ValidPath(name) || name == "." {
+			continue
+		}
+
+		switch header.Typeflag {
+		case tar.TypeDir:
+			archive.add(name, &memFileInfo{name: path.Base(name), mode: fs.ModeDir | 0755, modTime: header.ModTime}, nil)
+		case tar.TypeReg:
+			content, err := io.ReadAll(reader)
+			if err != nil {
+				return nil, err
+			}
+			archive.add(name, &memFileInfo{name: path.Base(name), size: header.Size, mode: header.FileInfo().Mode().Perm(), modTime: header.ModTime}, content)
+		case tar.TypeLink:
+			links[name] = path.Clean(strings.TrimPrefix(header.Linkname, "/"))
+		case tar.TypeSymlink:
+			links[name] = path.Join(path.Dir(name), header.Linkname)
+		}
+	}
+
+	// Links are resolved once every file they might point at has been read
+	for name, target := range links {
+		for hops := 0; hops < 40; hops++ {
+			next, isLink := links[target]
+			if !isLink {
+				break
+			}
+			target = next
+		}
+		if info, ok := archive.entries[target]; ok && !info.IsDir() {
+			linked := *info
+			linked.name = path.Base(name)
+			archive.add(name, &linked, archive.contents[target])
+		}
+	}
+
+	for _, names := range archive.children {
+		sort.Strings(names)
+	}
+	return archive, nil
+}
+
+// add records a file or directory, and any parent directories the archive didn't list
+func (a *tarFS) add(name string, info *memFileInfo, content []byte) {
+	if existing, ok := a.entries[name]; ok {
+		// A directory listed after its contents replaces the implied one
+		if existing.IsDir() && info.IsDir() {
+			*existing = *info
+		}
+		return
+	}
+
+	parent := path.Dir(name)
+	if _, ok := a.entries[parent]; !ok {
+		a.add(parent, &memFileInfo{name: path.Base(parent), mode: fs.ModeDir | 0755}, nil)
+	}
+
+	a.entries[name] = info
+	a.children[parent] = append(a.children[parent], name)
+	if !info.IsDir() {
+		a.contents[name] = content
+	}
+}
+
+// Open opens a file or directory in the archive
+func (a *tarFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	info, ok := a.entries[name]
+	if !ok {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrNotExist}
+	}
+	if info.IsDir() {
+		var entries []fs.DirEntry
+		for _, child := range a.children[name] {
+			entries = append(entries, fs.FileInfoToDirEntry(a.entries[child]))
+		}
+		return newMemFile(info, nil, entries), nil
+	}
+	return newMemFile(info, a.contents[name], nil), nil
+}
+
+// archiveEntry finds the entry file in an archive. Repro projects are often zipped with a single
+// top-level directory, so paths are also tried inside it.
+func archiveEntry(archive fs.FS, entry string) (string, error) {
+	name := path.Clean(strings.TrimPrefix(strings.ReplaceAll(entry, "\\", "/"), "/"))
+	if !fs.ValidPath(name) {
+		return "", fmt.Errorf("invalid path in archive: %s", entry)
+	}
+	if _, err := fs.Stat(archive, name); err == nil {
+		return name, nil
+	}
+
+	if top := archiveTopDir(archive); top != "" {
+		if _, err := fs.Stat(archive, top+"/"+name); err == nil {
+			return top + "/" + name, nil
+		}
+	}
+	return "", fmt.Errorf("%s not found in archive", entry)
+}
+
+// archiveTopDir returns the only directory at the top of an archive, if everything is inside one.
+// The resource forks macOS adds to zips are ignored.
+func archiveTopDir(archive fs.FS) string {
+	entries, err := fs.ReadDir(archive, ".")
+	if err != nil {
+		return ""
+	}
+	var dirs []fs.DirEntry
+	for _, entry := range entries {
+		if entry.Name() != "__MACOSX" {
+			dirs = append(dirs, entry)
+		}
+	}
+	if len(dirs) != 1 || !dirs[0].IsDir() {
+		return ""
+	}
+	return dirs[0].Name()
+}
@@ -0,0 +1,159 @@
+package main
+
+import (
+	"archive/tar"
+	"archive/zip"
+	"compress/gzip"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestMountArchive tests collecting files from zip and tar archives without extracting them
+func TestMountArchive(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "archive-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer func(original fs.FS) { fileSystem = original }(fileSystem)
+
+	// A project marker next to the archives must not be taken as their project root
+	if err := os.WriteFile(filepath.Join(tempDir, "go.mod"), []byte("module outside\n"), 0644); err != nil {
+		t.Fatalf("Failed to create go.mod: %v", err)
+	}
+
+	project := map[string]string{
+		"package.json":  `{"name": "repro"}`,
+		"src/app.ts":    "import { util } from './util';\nimport { shared } from '../lib/shared';\n",
+		"src/util.ts":   "export const util = 1;\n",
+		"lib/shared.ts": "export const shared = 1;\n",
+	}
+	loose := map[string]string{
+		"scripts/main.py":    "import helpers\n",
+		"scripts/helpers.py": "",
+	}
+
+	writeZip := func(name string, prefix string, files map[string]string) string {
+		archivePath := filepath.Join(tempDir, name)
+		file, err := os.Create(archivePath)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		defer file.Close()
+		writer := zip.NewWriter(file)
+		for path, content := range files {
+			w, err := writer.Create(prefix + path)
+			if err != nil {
+				t.Fatalf("Failed to add %s: %v", path, err)
+			}
+			w.Write([]byte(content))
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		return archivePath
+	}
+	writeTar := func(name string, prefix string, files map[string]string, links map[string]string) string {
+		archivePath := filepath.Join(tempDir, name)
+		file, err := os.Create(archivePath)
+		if err != nil {
+			t.Fatalf("Failed to create %s: %v", name, err)
+		}
+		defer file.Close()
+		gz := gzip.NewWriter(file)
+		writer := tar.NewWriter(gz)
+		for path, content := range files {
+			header := &tar.Header{Name: prefix + path, Mode: 0644, Size: int64(len(content)), ModTime: time.Now(), Typeflag: tar.TypeReg}
+			if err := writer.WriteHeader(header); err != nil {
+				t.Fatalf("Failed to add %s: %v", path, err)
+			}
+			writer.Write([]byte(content))
+		}
+		for path, target := range links {
+			header := &tar.Header{Name: prefix + path, Linkname: target, Mode: 0777, Typeflag: tar.TypeSymlink}
+			if err := writer.WriteHeader(header); err != nil {
+				t.Fatalf("Failed to add link %s: %v", path, err)
+			}
+		}
+		if err := writer.Close(); err != nil {
+			t.Fatalf("Failed to write %s: %v", name, err)
+		}
+		gz.Close()
+		return archivePath
+	}
+
+	tests := []struct {
+		name     string
+		archive  string
+		entry    string
+		root     string
+		expected []string
+	}{
+		{
+			name:     "zip with a top-level directory",
+			archive:  writeZip("repro.zip", "repro/", project),
+			entry:    "src/app.ts",
+			root:     "repro",
+			expected: []string{"repro/src/app.ts", "repro/src/util.ts", "repro/lib/shared.ts"},
+		},
+		{
+			name:     "tar.gz",
+			archive:  writeTar("repro.tar.gz", "", project, map[string]string{"src/alias.ts": "util.ts"}),
+			entry:    "src/app.ts",
+			root:     "",
+			expected: []string{"src/app.ts", "src/util.ts", "lib/shared.ts"},
+		},
+		{
+			name:     "tgz without a project marker",
+			archive:  writeTar("scripts.tgz", "./", loose, nil),
+			entry:    "/scripts/main.py",
+			root:     "scripts",
+			expected: []string{"scripts/main.py", "scripts/helpers.py"},
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileSystem = osFS{os.DirFS("/")}
+			entryPath, projectRoot, err := MountArchive(test.archive, test.entry)
+			if err != nil {
+				t.Fatalf("MountArchive failed: %v", err)
+			}
+			if expected := filepath.Join(test.archive, test.root); projectRoot != expected {
+				t.Errorf("Expected project root %s, got %s", expected, projectRoot)
+			}
+
+			processedFiles = make(map[string]struct{})
+			results := make(map[string]string)
+			if err := ProcessFile(entryPath, projectRoot, results); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+			if len(results) != len(test.expected) {
+				t.Errorf("Expected %d files, got %v", len(test.expected), resultPaths(results))
+			}
+			for _, path := range test.expected {
+				if _, ok := results[filepath.Join(test.archive, path)]; !ok {
+					t.Errorf("Expected %s to be included, got %v", path, resultPaths(results))
+				}
+			}
+		})
+	}
+
+	// Symbolic links in tar archives are read as the file they point to
+	fileSystem = osFS{os.DirFS("/")}
+	archive, err := openArchive(filepath.Join(tempDir, "repro.tar.gz"))
+	if err != nil {
+		t.Fatalf("openArchive failed: %v", err)
+	}
+	if content, err := fs.ReadFile(archive, "src/alias.ts"); err != nil || string(content) != project["src/util.ts"] {
+		t.Errorf("Expected src/alias.ts to read as src/util.ts, got %q (%v)", content, err)
+	}
+
+	if _, _, err := MountArchive(filepath.Join(tempDir, "repro.zip"), "src/missing.ts"); err == nil {
+		t.Errorf("Expected an error for an entry missing from the archive")
+	}
+}
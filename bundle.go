@@ -0,0 +1,128 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Bundle is a collected set of files in a form that can be saved and compared with another
+type Bundle struct {
+	// Root is the project root the file paths are relative to
+	Root string `json:"root"`
+	// Revision is the git revision the files were read at, when not the working tree
+	Revision string `json:"revision,omitempty"`
+	// Entries are the files collection started from
+	Entries []string     `json:"entries"`
+	Files   []BundleFile `json:"files"`
+}
+
+// BundleFile is a file of a bundle
+type BundleFile struct {
+	Path    string `json:"path"`
+	Content string `json:"content"`
+	// Imports are the collected files this file imports
+	Imports []string `json:"imports,omitempty"`
+	// External is the third-party package the file belongs to
+	External string `json:"external,omitempty"`
+	// Config marks tooling config files added with -with-config
+	Config  bool     `json:"config,omitempty"`
+	Diff    string   `json:"diff,omitempty"`
+	History []string `json:"history,omitempty"`
+}
+
+// NewBundle builds a bundle of the collected files, with paths relative to the project root
+func NewBundle(projectRoot string, entries []string, results map[string]string) *Bundle {
+	bundle := &Bundle{Root: projectRoot}
+	for _, entry := range entries {
+		bundle.Entries = append(bundle.Entries, bundlePath(projectRoot, entry))
+	}
+
+	for filePath, content := range results {
+		file := BundleFile{
+			Path:     bundlePath(projectRoot, filePath),
+			Content:  content,
+			External: externalFiles[filePath],
+			Diff:     fileDiffs[filePath],
+			History:  fileHistory[filePath],
+		}
+		if _, ok := configFiles[filePath]; ok {
+			file.Config = true
+		}
+		for _, importPath := range importGraph[filePath] {
+			if collected := collectedPath(importPath, results); collected != "" && collected != filePath {
+				imported := bundlePath(projectRoot, collected)
+				if !containsString(file.Imports, imported) {
+					file.Imports = append(file.Imports, imported)
+				}
+			}
+		}
+		sort.Strings(file.Imports)
+		bundle.Files = append(bundle.Files, file)
+	}
+	sort.Slice(bundle.Files, func(i, j int) bool { return bundle.Files[i].Path < bundle.Files[j].Path })
+
+	return bundle
+}
+
+// bundlePath returns a path relative to the project root with forward slashes, so bundles
+// collected in different checkouts can be compared. Paths outside the project stay absolute.
+func bundlePath(projectRoot string, path string) string {
+	rel, err := filepath.Rel(projectRoot, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return filepath.ToSlash(path)
+	}
+	return filepath.ToSlash(rel)
+}
+
+// collectedPath returns the collected file an import refers to, trying extensions and index
+// files the way ProcessFile does, or "" if it wasn't collected
+func collectedPath(importPath string, results map[string]string) string {
+	importPath = filepath.Clean(importPath)
+	if _, ok := results[importPath]; ok {
+		return importPath
+	}
+	for _, ext := range candidateExtensions() {
+		for _, candidate := range []string{importPath + ext, filepath.Join(importPath, "index"+ext)} {
+			if _, ok := results[candidate]; ok {
+				return candidate
+			}
+		}
+	}
+	return ""
+}
+
+// JSON encodes the bundle
+func (b *Bundle) JSON() (string, error) {
+	content, err := json.MarshalIndent(b, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(content) + "\n", nil
+}
+
+// file returns the bundle's file with the given path, or nil
+func (b *Bundle) file(path string) *BundleFile {
+	i := sort.Search(len(b.Files), func(i int) bool { return b.Files[i].Path >= path })
+	if i < len(b.Files) && b.Files[i].Path == path {
+		return &b.Files[i]
+	}
+	return nil
+}
+
+// LoadBundle reads a bundle saved with -format json
+func LoadBundle(path string) (*Bundle, error) {
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return nil, err
+	}
+	var bundle Bundle
+	if err := json.Unmarshal(content, &bundle); err != nil {
+		return nil, fmt.Errorf("invalid bundle %s: %v", path, err)
+	}
+	sort.Slice(bundle.Files, func(i, j int) bool { return bundle.Files[i].Path < bundle.Files[j].Path })
+	return &bundle, nil
+}
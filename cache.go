@@ -0,0 +1,276 @@
+package main
+
+import (
+	"crypto/sha256"
+	"encoding/hex"
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+	"sync"
+	"time"
+)
+
+// Version of the cache format and of how imports are resolved. Caches from other versions are ignored.
+const importCacheVersion = 2
+
+// Files that change how imports resolve for everything below them
+var resolutionManifests = []string{
+	".fixfiles.json",
+	"package.json",
+	"pnpm-workspace.yaml",
+	"tsconfig.json",
+	"go.mod",
+	"compile_commands.json",
+	"buf.yaml",
+	"buf.work.yaml",
+}
+
+// Cache of resolved imports for the current run, or nil when caching is off
+var importCache *ImportCache
+
+// ImportCache remembers the resolved imports of files between runs
+type ImportCache struct {
+	path        string
+	projectRoot string
+	// settings fingerprints the configuration, which affects every resolution
+	settings string
+
+	mu      sync.Mutex
+	files   map[string]importCacheEntry
+	changed bool
+	// Fingerprints of the manifests above each directory, worked out once per run
+	environments map[string]string
+	hits         int
+}
+
+// importCacheEntry is what's remembered about one file
+type importCacheEntry struct {
+	ModTime time.Time `json:"modTime"`
+	Size    int64     `json:"size"`
+	Hash    string    `json:"hash"`
+	// Environment fingerprints the settings and the manifests the file's imports were resolved with
+	Environment string   `json:"environment"`
+	Imports     []string `json:"imports"`
+	// Directories holds the modification times of the directories the imports were found or looked for in
+	Directories map[string]int64 `json:"directories,omitempty"`
+}
+
+// importCacheFile is the cache as saved on disk
+type importCacheFile struct {
+	Version     int                         `json:"version"`
+	ProjectRoot string                      `json:"projectRoot"`
+	Files       map[string]importCacheEntry `json:"files"`
+}
+
+// importCachePath returns where the cache for a project is kept: under the user cache
+// directory, or in .fixfiles/cache in the project if there isn't one
+func importCachePath(projectRoot string) string {
+	sum := sha256.Sum256([]byte(projectRoot))
+	name := hex.EncodeToString(sum[:8]) + ".json"
+	if dir, err := os.UserCacheDir(); err == nil {
+		return filepath.Join(dir, "fixfiles", name)
+	}
+	return filepath.Join(projectRoot, ".fixfiles", "cache", name)
+}
+
+// LoadImportCache reads the cache saved at path for a project. A missing, unreadable or outdated
+// cache gives an empty one. With an empty path the cache is only kept in memory.
+func LoadImportCache(path string, projectRoot string) *ImportCache {
+	cache := &ImportCache{
+		path:         path,
+		projectRoot:  projectRoot,
+		settings:     resolutionSettings(),
+		files:        make(map[string]importCacheEntry),
+		environments: make(map[string]string),
+	}
+
+	content, err := os.ReadFile(path)
+	if err != nil {
+		return cache
+	}
+	var saved importCacheFile
+	if err := json.Unmarshal(content, &saved); err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: ignoring unreadable cache %s: %v\n", path, err)
+		return cache
+	}
+	if saved.Version == importCacheVersion && saved.ProjectRoot == projectRoot && saved.Files != nil {
+		cache.files = saved.Files
+	}
+	return cache
+}
+
+// resolutionSettings fingerprints the settings that change how imports resolve, which invalidate
+// the whole cache
+func resolutionSettings() string {
+	resolution := config
+	resolution.WithTests, resolution.TestsEntryOnly, resolution.WithConfig, resolution.WithDiff = false, false, false, false
+	resolution.History, resolution.BlameContext, resolution.Jobs, resolution.NoCache = 0, 0, 0, false
+	settings, _ := json.Marshal(resolution)
+	return string(settings)
+}
+
+// newRun prepares the cache for collecting again in the same process, picking up changes to the
+// settings and manifests since the last run
+func (c *ImportCache) newRun() {
+	if c == nil {
+		return
+	}
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.settings = resolutionSettings()
+	c.environments = make(map[string]string)
+}
+
+// Save writes the cache back to disk if anything was added to it
+func (c *ImportCache) Save() error {
+	if c == nil || c.path == "" || !c.changed {
+		return nil
+	}
+	content, err := json.Marshal(importCacheFile{Version: importCacheVersion, ProjectRoot: c.projectRoot, Files: c.files})
+	if err != nil {
+		return err
+	}
+	if err := os.MkdirAll(filepath.Dir(c.path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a concurrent run never reads half a cache
+	temp := c.path + ".tmp"
+	if err := os.WriteFile(temp, content, 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, c.path)
+}
+
+// lookup returns the cached imports of a file, and the directories they were looked for in, if
+// neither it nor anything its imports were resolved with has changed
+func (c *ImportCache) lookup(path string, info fs.FileInfo, content []byte) ([]string, map[string]int64, bool) {
+	if c == nil {
+		return nil, nil, false
+	}
+	environment := c.environment(filepath.Dir(path))
+
+	c.mu.Lock()
+	entry, ok := c.files[path]
+	c.mu.Unlock()
+	if !ok || entry.Size != info.Size() || !entry.ModTime.Equal(info.ModTime()) ||
+		entry.Environment != environment || entry.Hash != contentHash(content) {
+		return nil, nil, false
+	}
+
+	// A file added, removed or renamed where an import was looked for may change what it resolves to
+	if !directoriesUnchanged(entry.Directories) {
+		return nil, nil, false
+	}
+
+	c.mu.Lock()
+	c.hits++
+	c.mu.Unlock()
+	return entry.Imports, entry.Directories, true
+}
+
+// store remembers the resolved imports of a file and the directories they were looked for in
+func (c *ImportCache) store(path string, info fs.FileInfo, content []byte, imports []string, dirs map[string]int64) {
+	if c == nil {
+		return
+	}
+	entry := importCacheEntry{
+		ModTime:     info.ModTime(),
+		Size:        info.Size(),
+		Hash:        contentHash(content),
+		Environment: c.environment(filepath.Dir(path)),
+		Imports:     imports,
+		Directories: dirs,
+	}
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.files[path] = entry
+	c.changed = true
+}
+
+// environment fingerprints the settings and manifests imports in dir are resolved with
+func (c *ImportCache) environment(dir string) string {
+	c.mu.Lock()
+	fingerprint, ok := c.environments[dir]
+	c.mu.Unlock()
+	if ok {
+		return fingerprint
+	}
+
+	fingerprint = resolutionEnvironment(c.settings, dir, c.projectRoot)
+
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	c.environments[dir] = fingerprint
+	return fingerprint
+}
+
+// resolutionEnvironment fingerprints settings and every resolution manifest in dir and the
+// directories above it up to the project root, including which ones don't exist
+func resolutionEnvironment(settings string, dir string, projectRoot string) string {
+	var builder strings.Builder
+	builder.WriteString(settings)
+	for current := dir; ; current = filepath.Dir(current) {
+		for _, name := range resolutionManifests {
+			path := filepath.Join(current, name)
+			if info, err := statFile(path); err == nil {
+				fmt.Fprintf(&builder, "\n%s %d %d", path, info.Size(), info.ModTime().UnixNano())
+			}
+		}
+		if current == projectRoot || filepath.Dir(current) == current {
+			break
+		}
+	}
+	return contentHash([]byte(builder.String()))
+}
+
+// resolutionDirectories returns the modification times of the directories holding each path, and
+// of the directories above them. The paths are the resolved imports of a file and the paths its
+// resolvers searched, so creating a higher priority candidate changes one of them: b.ts for an
+// import of ./b that found b.js, utils.ts for one that found utils/index.ts, pkg/util.py next to
+// a Python file importing the root util.py, or lib/helper.rb for a require that found nothing.
+func resolutionDirectories(paths []string) map[string]int64 {
+	if len(paths) == 0 {
+		return nil
+	}
+	dirs := make(map[string]int64)
+	for _, imported := range paths {
+		dir := filepath.Dir(imported)
+		for _, candidate := range []string{dir, filepath.Dir(dir)} {
+			if _, ok := dirs[candidate]; !ok {
+				dirs[candidate] = directoryModTime(candidate)
+			}
+		}
+	}
+	return dirs
+}
+
+// directoriesUnchanged reports whether directories still have the modification times recorded by
+// resolutionDirectories
+func directoriesUnchanged(dirs map[string]int64) bool {
+	for dir, modTime := range dirs {
+		if directoryModTime(dir) != modTime {
+			return false
+		}
+	}
+	return true
+}
+
+// directoryModTime returns the modification time of a directory, or 0 if it doesn't exist
+func directoryModTime(dir string) int64 {
+	info, err := statFile(dir)
+	if err != nil {
+		return 0
+	}
+	return info.ModTime().UnixNano()
+}
+
+// contentHash returns the SHA-256 of content in hex
+func contentHash(content []byte) string {
+	sum := sha256.Sum256(content)
+	return hex.EncodeToString(sum[:])
+}
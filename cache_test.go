@@ -0,0 +1,261 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+)
+
+// TestImportCache tests reusing resolved imports between runs and invalidating them
+func TestImportCache(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "import-cache-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer func() { importCache = nil }()
+	defer func(original Config) { config = original }(config)
+
+	write := func(path string, content string) {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+	write("package.json", `{"name": "app"}`)
+	write("tsconfig.json", `{"compilerOptions": {}}`)
+	write("src/app.ts", "import { util } from './util';\nimport { other } from './other';\n")
+	write("src/util.ts", "export const util = 1;\n")
+	write("src/other.ts", "export const other = 1;\n")
+
+	// The cache is kept outside the project, as creating it would change the project's directories
+	cacheDir, err := os.MkdirTemp("", "import-cache")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+	cachePath := filepath.Join(cacheDir, "cache", "imports.json")
+
+	// run collects the project with a freshly loaded cache and returns the number of cache hits
+	run := func() (int, map[string]string) {
+		t.Helper()
+		resetCollection()
+		importCache = LoadImportCache(cachePath, tempDir)
+		results := make(map[string]string)
+		if err := ProcessFile(filepath.Join(tempDir, "src/app.ts"), tempDir, results); err != nil {
+			t.Fatalf("ProcessFile failed: %v", err)
+		}
+		if err := importCache.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		return importCache.hits, results
+	}
+
+	config = Config{}
+	if hits, results := run(); hits != 0 || len(results) != 3 {
+		t.Fatalf("Expected 3 files and no hits on the first run, got %d files and %d hits", len(results), hits)
+	}
+	if _, err := os.Stat(cachePath); err != nil {
+		t.Fatalf("Expected the cache to be saved: %v", err)
+	}
+
+	tests := []struct {
+		name   string
+		change func()
+		hits   int
+		files  int
+	}{
+		{"unchanged", func() {}, 3, 3},
+		{"settings that don't affect resolution", func() { config.Jobs = 2; config.WithTests = true }, 3, 3},
+		{"changed file", func() { write("src/util.ts", "export const util = 22;\n") }, 2, 3},
+		{"changed manifest", func() { write("tsconfig.json", `{"compilerOptions": {"strict": true}}`) }, 0, 3},
+		{"new manifest", func() { write("src/package.json", `{"name": "nested"}`) }, 0, 3},
+		{"changed settings", func() { config.Rails = true }, 0, 3},
+		{"deleted import", func() { os.Remove(filepath.Join(tempDir, "src/other.ts")) }, 1, 2},
+		{"created import", func() {
+			// Make sure the directory's modification time moves on
+			os.Chtimes(filepath.Join(tempDir, "src"), time.Unix(0, 0), time.Unix(0, 0))
+			write("src/other.ts", "export const other = 2;\n")
+		}, 1, 3},
+	}
+
+	for _, test := range tests {
+		test.change()
+		// The first run after a change repopulates the cache, the second should hit it fully
+		hits, results := run()
+		if hits != test.hits || len(results) != test.files {
+			t.Errorf("%s: expected %d hits and %d files, got %d hits and %d files", test.name, test.hits, test.files, hits, len(results))
+		}
+		if hits, _ := run(); hits != test.files {
+			t.Errorf("%s: expected %d hits once the cache was updated, got %d", test.name, test.files, hits)
+		}
+	}
+
+	// A corrupt cache is ignored
+	if err := os.WriteFile(cachePath, []byte("{not json"), 0644); err != nil {
+		t.Fatalf("Failed to corrupt the cache: %v", err)
+	}
+	if hits, results := run(); hits != 0 || len(results) != 3 {
+		t.Errorf("Expected a corrupt cache to be ignored, got %d hits and %d files", hits, len(results))
+	}
+}
+
+// TestImportCacheNewCandidate tests that a cached import is resolved again when a file it would
+// now resolve to is added
+func TestImportCacheNewCandidate(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "import-cache-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	cacheDir, err := os.MkdirTemp("", "import-cache")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(cacheDir)
+	defer func() { importCache = nil }()
+
+	write := func(path string, content string) {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+	write("package.json", `{"name": "app"}`)
+	write("src/app.ts", "import { b } from './b';\nimport { util } from './utils';\n")
+	write("src/b.js", "export const b = 1;\n")
+	write("src/utils/index.ts", "export const util = 1;\n")
+
+	collect := func() map[string]string {
+		t.Helper()
+		resetCollection()
+		importCache = LoadImportCache(filepath.Join(cacheDir, "imports.json"), tempDir)
+		results := make(map[string]string)
+		if err := ProcessFile(filepath.Join(tempDir, "src/app.ts"), tempDir, results); err != nil {
+			t.Fatalf("ProcessFile failed: %v", err)
+		}
+		if err := importCache.Save(); err != nil {
+			t.Fatalf("Save failed: %v", err)
+		}
+		return results
+	}
+	collect()
+
+	tests := []struct {
+		name    string
+		add     string
+		replace string
+	}{
+		{"higher priority extension", "src/b.ts", "src/b.js"},
+		{"file instead of directory index", "src/utils.ts", "src/utils/index.ts"},
+	}
+	for _, test := range tests {
+		// Make sure the directory's modification time moves on
+		dir := filepath.Dir(filepath.Join(tempDir, test.add))
+		os.Chtimes(dir, time.Unix(0, 0), time.Unix(0, 0))
+		write(test.add, "export const b = 2, util = 2;\n")
+
+		results := collect()
+		if _, ok := results[filepath.Join(tempDir, test.add)]; !ok {
+			t.Errorf("%s: expected %s to be collected, got %v", test.name, test.add, resultPaths(results))
+		}
+		if _, ok := results[filepath.Join(tempDir, test.replace)]; ok {
+			t.Errorf("%s: expected %s to no longer be collected", test.name, test.replace)
+		}
+	}
+}
+
+// TestImportCacheSearchedDirectories tests that cached imports are resolved again when a file is
+// created where a resolver looked for an import, in languages that search several directories
+func TestImportCacheSearchedDirectories(t *testing.T) {
+	defer func() { importCache = nil }()
+
+	tests := []struct {
+		name  string
+		files map[string]string
+		entry string
+		// add is created after the first run and replaces the file collected before, if any
+		add     string
+		replace string
+	}{
+		{
+			name:  "Ruby require found on a load path",
+			files: map[string]string{"Gemfile": "", "app/main.rb": "require 'helper'\n"},
+			entry: "app/main.rb",
+			add:   "lib/helper.rb",
+		},
+		{
+			name:    "Python module next to the importing file",
+			files:   map[string]string{"setup.py": "", "util.py": "", "pkg/sub/main.py": "import util\n"},
+			entry:   "pkg/sub/main.py",
+			add:     "pkg/sub/util.py",
+			replace: "util.py",
+		},
+		{
+			name:  "C include next to the including file",
+			files: map[string]string{"Makefile": "", "src/main.c": "#include \"config.h\"\n"},
+			entry: "src/main.c",
+			add:   "src/config.h",
+		},
+	}
+
+	for _, test := range tests {
+		tempDir, err := os.MkdirTemp("", "import-cache-test")
+		if err != nil {
+			t.Fatalf("Failed to create temp directory: %v", err)
+		}
+		defer os.RemoveAll(tempDir)
+		cachePath := filepath.Join(t.TempDir(), "imports.json")
+
+		write := func(path string, content string) {
+			fullPath := filepath.Join(tempDir, path)
+			if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+				t.Fatalf("Failed to create directory for %s: %v", path, err)
+			}
+			if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+				t.Fatalf("Failed to create file %s: %v", path, err)
+			}
+		}
+		for path, content := range test.files {
+			write(path, content)
+		}
+		collect := func() map[string]string {
+			t.Helper()
+			resetCollection()
+			importCache = LoadImportCache(cachePath, tempDir)
+			results := make(map[string]string)
+			if err := ProcessFile(filepath.Join(tempDir, test.entry), tempDir, results); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+			if err := importCache.Save(); err != nil {
+				t.Fatalf("Save failed: %v", err)
+			}
+			return results
+		}
+		collect()
+
+		// Make sure the directory's modification time moves on, if it already exists
+		dir := filepath.Dir(filepath.Join(tempDir, test.add))
+		os.Chtimes(dir, time.Unix(0, 0), time.Unix(0, 0))
+		write(test.add, "\n")
+
+		results := collect()
+		if importCache.hits != 0 {
+			t.Errorf("%s: expected the entry file's cached imports not to be used", test.name)
+		}
+		if _, ok := results[filepath.Join(tempDir, test.add)]; !ok {
+			t.Errorf("%s: expected %s to be collected, got %v", test.name, test.add, resultPaths(results))
+		}
+		if _, ok := results[filepath.Join(tempDir, test.replace)]; test.replace != "" && ok {
+			t.Errorf("%s: expected %s to no longer be collected", test.name, test.replace)
+		}
+	}
+}
@@ -0,0 +1,89 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"os"
+	"path/filepath"
+)
+
+// Name of the optional per-project configuration file
+const configFileName = ".fixfiles.json"
+
+// Config holds project-level settings that change how imports are resolved
+type Config struct {
+	// Rails enables Zeitwerk-style resolution of constant references in Ruby files
+	Rails bool `json:"rails"`
+
+	// IncludePaths are extra directories, relative to the project root, searched for C/C++ includes
+	IncludePaths []string `json:"includePaths"`
+
+	// PairSources includes the implementation file for each included C/C++ header
+	PairSources bool `json:"pairSources"`
+
+	// IncludeAssets follows references to images, fonts and media from HTML
+	IncludeAssets bool `json:"includeAssets"`
+
+	// StyleLoadPaths are directories, relative to the project root, searched for Sass and Less imports
+	StyleLoadPaths []string `json:"styleLoadPaths"`
+
+	// IncludeExternal lists third-party packages whose sources may be followed
+	IncludeExternal []string `json:"includeExternal"`
+
+	// IncludeExternalDepth is how many imports to follow into third-party code, defaulting to 1
+	IncludeExternalDepth int `json:"includeExternalDepth"`
+
+	// Types lists packages whose bundled or @types declarations are included for TypeScript imports
+	Types []string `json:"types"`
+
+	// ProtoPaths are directories, relative to the project root, searched for protobuf imports like protoc's -I
+	ProtoPaths []string `json:"protoPaths"`
+
+	// WithTests includes the tests of the collected files, found by naming convention
+	WithTests bool `json:"withTests"`
+
+	// TestsEntryOnly limits WithTests to the tests of the entry file
+	TestsEntryOnly bool `json:"testsEntryOnly"`
+
+	// WithConfig includes the nearest build and tooling config files for the collected languages
+	WithConfig bool `json:"withConfig"`
+
+	// WithDiff includes the unified diff of each changed file when run with -changed
+	WithDiff bool `json:"withDiff"`
+
+	// History is how many recent commits to list for each collected file
+	History int `json:"history"`
+
+	// BlameContext is how many lines around a -blame location to blame, defaulting to 5
+	BlameContext int `json:"blameContext"`
+
+	// Jobs is how many files are read and parsed at once, defaulting to the number of CPUs
+	Jobs int `json:"jobs"`
+
+	// NoCache turns off the cache of resolved imports kept between runs
+	NoCache bool `json:"noCache"`
+
+	// Languages declares import rules for file types fixfiles doesn't know about
+	Languages []LanguageRule `json:"languages"`
+}
+
+// Active configuration for the current run
+var config Config
+
+// LoadConfig reads the config file from the project root, if one exists
+func LoadConfig(projectRoot string) (Config, error) {
+	var cfg Config
+
+	content, err := readFile(filepath.Join(projectRoot, configFileName))
+	if os.IsNotExist(err) {
+		return cfg, nil
+	} else if err != nil {
+		return cfg, err
+	}
+
+	if err := json.Unmarshal(content, &cfg); err != nil {
+		return cfg, fmt.Errorf("invalid %s: %v", configFileName, err)
+	}
+
+	return cfg, nil
+}
@@ -0,0 +1,121 @@
+package main
+
+import (
+	"path/filepath"
+	"sort"
+)
+
+// Files collected as tooling configuration rather than code
+var configFiles = make(map[string]struct{})
+
+// Build and tooling config files that affect how the sources of a language are compiled or run
+var toolingConfigs = []struct {
+	extensions []string
+	files      []string
+}{
+	{
+		extensions: []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts", ".vue", ".svelte", ".astro"},
+		files: []string{
+			"package.json", "tsconfig.json", "jsconfig.json",
+			".babelrc", "babel.config.js", "babel.config.json", "babel.config.cjs",
+			"vite.config.ts", "vite.config.js", "vite.config.mjs",
+			"webpack.config.js", "webpack.config.ts", "webpack.config.cjs",
+			"jest.config.js", "jest.config.ts", "jest.config.cjs", "jest.config.mjs", "jest.config.json",
+			"vitest.config.ts", "vitest.config.js",
+			"next.config.js", "next.config.mjs", "next.config.ts",
+			"vue.config.js", "svelte.config.js", "astro.config.mjs",
+		},
+	},
+	{
+		extensions: []string{".css", ".scss", ".sass", ".less"},
+		files:      []string{"postcss.config.js", "postcss.config.cjs", "tailwind.config.js", "tailwind.config.ts"},
+	},
+	{
+		extensions: []string{".go"},
+		files:      []string{"go.mod", "go.work"},
+	},
+	{
+		extensions: []string{".py"},
+		files:      []string{"pyproject.toml", "setup.cfg", "setup.py", "requirements.txt", "pytest.ini", "tox.ini", "mypy.ini"},
+	},
+	{
+		extensions: []string{".rb"},
+		files:      []string{"Gemfile", ".rubocop.yml"},
+	},
+	{
+		extensions: []string{".rs"},
+		files:      []string{"Cargo.toml"},
+	},
+	{
+		extensions: []string{".java", ".kt"},
+		files:      []string{"pom.xml", "build.gradle", "build.gradle.kts", "settings.gradle", "settings.gradle.kts"},
+	},
+	{
+		extensions: []string{".php"},
+		files:      []string{"composer.json"},
+	},
+	{
+		extensions: []string{".c", ".h", ".cc", ".cpp", ".cxx", ".hh", ".hpp", ".hxx"},
+		files:      []string{"CMakeLists.txt", "meson.build", "Makefile"},
+	},
+	{
+		extensions: []string{".cs"},
+		files:      []string{"Directory.Build.props", "global.json"},
+	},
+	{
+		extensions: []string{".proto"},
+		files:      []string{"buf.yaml", "buf.work.yaml"},
+	},
+	{
+		extensions: []string{".graphql", ".gql"},
+		files:      []string{".graphqlrc.yml", ".graphqlrc.json", "codegen.yml", "codegen.ts"},
+	},
+}
+
+// IncludeConfigFiles adds the nearest tooling config files for the languages of the collected files
+func IncludeConfigFiles(projectRoot string, results map[string]string) {
+	var sources []string
+	for path := range results {
+		if _, external := externalFiles[path]; !external {
+			sources = append(sources, path)
+		}
+	}
+	sort.Strings(sources)
+
+	for _, source := range sources {
+		ext := fileExtension(source)
+		for _, tooling := range toolingConfigs {
+			if !containsString(tooling.extensions, ext) {
+				continue
+			}
+			for _, name := range tooling.files {
+				path := nearestFile(filepath.Dir(source), projectRoot, name)
+				if path == "" {
+					continue
+				}
+				if _, ok := results[path]; ok {
+					continue
+				}
+				if content, err := readFile(path); err == nil {
+					results[path] = string(content)
+					configFiles[path] = struct{}{}
+					processedFiles[path] = struct{}{}
+				}
+			}
+		}
+	}
+}
+
+// nearestFile looks for a file named name in dir and its parents up to the project root
+func nearestFile(dir string, projectRoot string, name string) string {
+	for {
+		path := filepath.Join(dir, name)
+		if info, err := statFile(path); err == nil && !info.IsDir() {
+			return path
+		}
+		if dir == projectRoot || filepath.Dir(dir) == dir {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
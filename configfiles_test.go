@@ -0,0 +1,77 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestIncludeConfigFiles tests adding the nearest tooling config files for the collected languages
+func TestIncludeConfigFiles(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "config-files-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer func() { configFiles = make(map[string]struct{}) }()
+
+	files := map[string]string{
+		"package.json":            `{"name": "monorepo"}`,
+		"tsconfig.json":           `{"compilerOptions": {"strict": true}}`,
+		"pyproject.toml":          "[project]\nname = \"tools\"\n",
+		"apps/web/package.json":   `{"name": "web"}`,
+		"apps/web/vite.config.ts": "export default {};",
+		"apps/web/src/main.ts":    "import { api } from './api';",
+		"apps/web/src/api.ts":     "export const api = 1;",
+		"apps/web/src/style.css":  "body {}",
+		"apps/server/go.mod":      "module example.com/server\n",
+		"apps/server/cmd/main.go": "package main",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	processedFiles = make(map[string]struct{})
+	configFiles = make(map[string]struct{})
+	results := make(map[string]string)
+	if err := ProcessFile(filepath.Join(tempDir, "apps/web/src/main.ts"), tempDir, results); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	IncludeConfigFiles(tempDir, results)
+
+	// The nearest package.json wins; tsconfig.json is only found at the root. Python and Go
+	// configs aren't relevant to the collected files.
+	expected := []string{"apps/web/package.json", "tsconfig.json", "apps/web/vite.config.ts"}
+	if len(configFiles) != len(expected) {
+		t.Fatalf("Expected %d config files, got %v", len(expected), configFiles)
+	}
+	for _, path := range expected {
+		if _, ok := configFiles[filepath.Join(tempDir, path)]; !ok {
+			t.Errorf("Expected config file %s, got %v", path, configFiles)
+		}
+	}
+
+	// Config files are listed after the code in their own section
+	output := FormatResults(results)
+	section := strings.Index(output, "\nConfiguration\n")
+	if section < 0 {
+		t.Fatalf("Expected a Configuration section in output")
+	}
+	if strings.Index(output, "apps/web/src/api.ts }}") > section {
+		t.Errorf("Expected code before the Configuration section")
+	}
+	if strings.Index(output, "vite.config.ts }}") < section {
+		t.Errorf("Expected config files in the Configuration section")
+	}
+	if !strings.Contains(output, "Generated 2 lines of code from 2 files and 3 config files") {
+		t.Errorf("Expected the summary to count config files and their lines separately")
+	}
+}
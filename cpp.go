@@ -0,0 +1,252 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matches quoted #include directives; <system> includes are deliberately not matched
+var cIncludePattern = regexp.MustCompile(`(?m)^\s*#\s*include\s*"([^"]+)"`)
+
+// File extensions for C and C++ headers and their implementation files
+var (
+	cHeaderExtensions = []string{".h", ".hh", ".hpp", ".hxx"}
+	cSourceExtensions = []string{".c", ".cc", ".cpp", ".cxx"}
+)
+
+// compileCommand is a single entry of a compile_commands.json compilation database
+type compileCommand struct {
+	Directory string   `json:"directory"`
+	File      string   `json:"file"`
+	Command   string   `json:"command"`
+	Arguments []string `json:"arguments"`
+}
+
+// includePaths holds the search directories for quoted includes
+type includePaths struct {
+	// Search paths from -iquote, which only apply to quoted includes
+	quote []string
+	// Search paths from -I
+	dirs []string
+}
+
+// Cache of include paths per project root, keyed by source file with "" holding the union
+var cIncludePaths = make(map[string]map[string]includePaths)
+
+// cLanguage resolves the quoted includes of C and C++ files along the compiler's include paths
+type cLanguage struct{}
+
+func init() {
+	RegisterLanguage(cLanguage{})
+}
+
+// Name identifies the language
+func (cLanguage) Name() string {
+	return "C/C++"
+}
+
+// Detect reports whether a file is a C or C++ source or header
+func (cLanguage) Detect(fileExt string) bool {
+	return containsString(cHeaderExtensions, fileExt) || containsString(cSourceExtensions, fileExt)
+}
+
+// ExtractSpecifiers returns the headers named by quoted #include directives
+func (cLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+	for _, match := range cIncludePattern.FindAllSubmatch(file.Content, -1) {
+		specs = append(specs, Specifier{Path: string(match[1])})
+	}
+	return specs
+}
+
+// IsBuiltin is always false, as <system> includes are never extracted
+func (cLanguage) IsBuiltin(spec Specifier) bool {
+	return false
+}
+
+// ResolveSpecifier finds an included header, and its implementation files when sources are paired
+func (cLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	paths := includePathsFor(file.Path, file.ProjectRoot)
+
+	// Quoted includes search the including directory first, then -iquote, -I and configured paths
+	searchDirs := []string{filepath.Dir(file.Path)}
+	searchDirs = append(searchDirs, paths.quote...)
+	searchDirs = append(searchDirs, paths.dirs...)
+	for _, dir := range config.IncludePaths {
+		searchDirs = append(searchDirs, filepath.Join(file.ProjectRoot, dir))
+	}
+
+	for _, dir := range searchDirs {
+		candidate := filepath.Join(dir, spec.Path)
+		file.Searched(candidate)
+		if info, err := statFile(candidate); err == nil && !info.IsDir() {
+			if config.PairSources {
+				return append([]string{candidate}, pairedSources(candidate)...)
+			}
+			return []string{candidate}
+		}
+	}
+	return nil
+}
+
+// pairedSources finds the implementation files matching a header, next to it or in a mirrored src/ directory
+func pairedSources(headerPath string) []string {
+	ext := filepath.Ext(headerPath)
+	if !containsString(cHeaderExtensions, ext) {
+		return nil
+	}
+	base := strings.TrimSuffix(headerPath, ext)
+
+	bases := []string{base}
+	sep := string(filepath.Separator)
+	if strings.Contains(base, sep+"include"+sep) {
+		bases = append(bases, strings.Replace(base, sep+"include"+sep, sep+"src"+sep, 1))
+	}
+
+	var sources []string
+	for _, candidateBase := range bases {
+		for _, sourceExt := range cSourceExtensions {
+			if _, err := statFile(candidateBase + sourceExt); err == nil {
+				sources = append(sources, candidateBase+sourceExt)
+			}
+		}
+	}
+	return sources
+}
+
+// includePathsFor returns the include paths used to compile a file, falling back to
+// the union of all paths in the compilation database when the file isn't listed
+func includePathsFor(filePath string, projectRoot string) includePaths {
+	byFile, ok := cacheLoad(cIncludePaths, projectRoot)
+	if !ok {
+		byFile = loadCompileCommands(projectRoot)
+		cacheStore(cIncludePaths, projectRoot, byFile)
+	}
+
+	if paths, ok := byFile[filePath]; ok {
+		return paths
+	}
+	return byFile[""]
+}
+
+// loadCompileCommands reads compile_commands.json from the project root or its build directory
+func loadCompileCommands(projectRoot string) map[string]includePaths {
+	byFile := make(map[string]includePaths)
+
+	var content []byte
+	for _, dir := range []string{projectRoot, filepath.Join(projectRoot, "build")} {
+		data, err := readFile(filepath.Join(dir, "compile_commands.json"))
+		if err == nil {
+			content = data
+			break
+		}
+	}
+	if content == nil {
+		return byFile
+	}
+
+	var commands []compileCommand
+	if err := json.Unmarshal(content, &commands); err != nil {
+		return byFile
+	}
+
+	var union includePaths
+	for _, command := range commands {
+		args := command.Arguments
+		if len(args) == 0 {
+			args = splitCommandLine(command.Command)
+		}
+		paths := parseIncludeFlags(args, command.Directory)
+
+		file := command.File
+		if !filepath.IsAbs(file) {
+			file = filepath.Join(command.Directory, file)
+		}
+		byFile[filepath.Clean(file)] = paths
+
+		for _, dir := range paths.quote {
+			if !containsString(union.quote, dir) {
+				union.quote = append(union.quote, dir)
+			}
+		}
+		for _, dir := range paths.dirs {
+			if !containsString(union.dirs, dir) {
+				union.dirs = append(union.dirs, dir)
+			}
+		}
+	}
+	byFile[""] = union
+
+	return byFile
+}
+
+// parseIncludeFlags extracts -I and -iquote directories from compiler arguments; -isystem is skipped
+func parseIncludeFlags(args []string, workingDir string) includePaths {
+	var paths includePaths
+
+	resolve := func(dir string) string {
+		if !filepath.IsAbs(dir) {
+			dir = filepath.Join(workingDir, dir)
+		}
+		return filepath.Clean(dir)
+	}
+
+	for i := 0; i < len(args); i++ {
+		arg := args[i]
+		switch {
+		case arg == "-I" || arg == "-iquote":
+			if i+1 < len(args) {
+				if arg == "-I" {
+					paths.dirs = append(paths.dirs, resolve(args[i+1]))
+				} else {
+					paths.quote = append(paths.quote, resolve(args[i+1]))
+				}
+				i++
+			}
+		case strings.HasPrefix(arg, "-iquote"):
+			paths.quote = append(paths.quote, resolve(strings.TrimPrefix(arg, "-iquote")))
+		case strings.HasPrefix(arg, "-I"):
+			paths.dirs = append(paths.dirs, resolve(strings.TrimPrefix(arg, "-I")))
+		}
+	}
+
+	return paths
+}
+
+// splitCommandLine splits a shell command into arguments, honouring simple quoting
+func splitCommandLine(command string) []string {
+	var args []string
+	var current strings.Builder
+	var quote rune
+	inArg := false
+
+	for _, r := range command {
+		switch {
+		case quote != 0:
+			if r == quote {
+				quote = 0
+			} else {
+				current.WriteRune(r)
+			}
+		case r == '"' || r == '\'':
+			quote = r
+			inArg = true
+		case r == ' ' || r == '\t' || r == '\n':
+			if inArg {
+				args = append(args, current.String())
+				current.Reset()
+				inArg = false
+			}
+		default:
+			current.WriteRune(r)
+			inArg = true
+		}
+	}
+	if inArg {
+		args = append(args, current.String())
+	}
+
+	return args
+}
@@ -0,0 +1,99 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtractCImports tests include resolution via compile_commands.json and header pairing
+func TestExtractCImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "c-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	compileCommands := `[
+  {
+    "directory": "` + tempDir + `",
+    "file": "src/main.c",
+    "command": "cc -Iinclude -isystem /usr/local/include -c src/main.c"
+  }
+]`
+
+	files := map[string]string{
+		"compile_commands.json": compileCommands,
+		"src/main.c":            "",
+		"src/util.h":            "int util(void);",
+		"include/lib/math.h":    "int add(int a, int b);",
+		"src/lib/math.c":        "int add(int a, int b) { return a + b; }",
+		"third_party/vendor.h":  "",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	mainPath := filepath.Join(tempDir, "src", "main.c")
+	mainContent := []byte(`
+#include <stdio.h>
+#include "util.h"
+#  include "lib/math.h"
+#include "vendor.h"
+`)
+
+	// vendor.h is only found once its directory is configured
+	config = Config{}
+	imports := extractImportsFromContent(mainPath, ".c", mainContent, tempDir)
+	expected := []string{
+		filepath.Join(tempDir, "src", "util.h"),
+		filepath.Join(tempDir, "include", "lib", "math.h"),
+	}
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for _, path := range expected {
+		if !containsString(imports, path) {
+			t.Errorf("Expected to find %s in imports", path)
+		}
+	}
+
+	// With configured include paths and pairing, vendor.h and math.c are included too
+	config = Config{IncludePaths: []string{"third_party"}, PairSources: true}
+	defer func() { config = Config{} }()
+	imports = extractImportsFromContent(mainPath, ".c", mainContent, tempDir)
+	expected = append(expected,
+		filepath.Join(tempDir, "src", "lib", "math.c"),
+		filepath.Join(tempDir, "third_party", "vendor.h"),
+	)
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for _, path := range expected {
+		if !containsString(imports, path) {
+			t.Errorf("Expected to find %s in imports", path)
+		}
+	}
+}
+
+// TestSplitCommandLine tests splitting compile commands into arguments
+func TestSplitCommandLine(t *testing.T) {
+	args := splitCommandLine(`cc -I "include dir" -DNAME='a b' -c main.c`)
+	expected := []string{"cc", "-I", "include dir", "-DNAME=a b", "-c", "main.c"}
+
+	if len(args) != len(expected) {
+		t.Fatalf("Expected %d arguments, got %d: %q", len(expected), len(args), args)
+	}
+	for i := range expected {
+		if args[i] != expected[i] {
+			t.Errorf("Argument %d: got %q, want %q", i, args[i], expected[i])
+		}
+	}
+}
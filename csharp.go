@@ -0,0 +1,305 @@
+package main
+
+import (
+	"encoding/xml"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matches using directives, including global, static and alias forms
+var csharpUsingPattern = regexp.MustCompile(`(?m)^\s*(?:global\s+)?using\s+(?:static\s+)?(?:\w+\s*=\s*)?([\w.]+)\s*;`)
+
+// Matches block and file-scoped namespace declarations
+var csharpNamespacePattern = regexp.MustCompile(`(?m)^\s*namespace\s+([\w.]+)`)
+
+// Matches project entries in a solution file
+var slnProjectPattern = regexp.MustCompile(`(?m)^Project\("\{[^}]*\}"\)\s*=\s*"[^"]*",\s*"([^"]+\.csproj)"`)
+
+// csharpProject describes a .csproj file and the sources it compiles
+type csharpProject struct {
+	path       string
+	references []string
+	sources    []string
+	// Maps each namespace declared in the project to the files declaring it
+	namespaces map[string][]string
+}
+
+// csprojFile is the subset of the MSBuild project format we need
+type csprojFile struct {
+	Sdk            string `xml:"Sdk,attr"`
+	PropertyGroups []struct {
+		EnableDefaultCompileItems string `xml:"EnableDefaultCompileItems"`
+	} `xml:"PropertyGroup"`
+	ItemGroups []struct {
+		ProjectReferences []struct {
+			Include string `xml:"Include,attr"`
+		} `xml:"ProjectReference"`
+		Compiles []struct {
+			Include string `xml:"Include,attr"`
+			Remove  string `xml:"Remove,attr"`
+		} `xml:"Compile"`
+	} `xml:"ItemGroup"`
+}
+
+// Parsed projects keyed by .csproj path
+var csharpProjects = make(map[string]*csharpProject)
+
+// csharpLanguage resolves using directives to the files declaring the namespaces, and project
+// and solution files to the projects they reference
+type csharpLanguage struct{}
+
+func init() {
+	RegisterLanguage(csharpLanguage{})
+}
+
+// Name identifies the language
+func (csharpLanguage) Name() string {
+	return "C#"
+}
+
+// Detect reports whether a file is C# source or an MSBuild project or solution
+func (csharpLanguage) Detect(fileExt string) bool {
+	return fileExt == ".cs" || fileExt == ".csproj" || fileExt == ".sln"
+}
+
+// ExtractSpecifiers returns the projects of a solution, the references of a project, or the
+// owning project and using directives of a source file
+func (csharpLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+
+	switch file.Ext {
+	case ".sln":
+		for _, match := range slnProjectPattern.FindAllSubmatch(file.Content, -1) {
+			specs = append(specs, Specifier{Path: msbuildPath(string(match[1])), Kind: "solution"})
+		}
+	case ".csproj":
+		if project := loadCSharpProject(file.Path); project != nil {
+			for _, reference := range project.references {
+				specs = append(specs, Specifier{Path: reference, Kind: "reference"})
+			}
+		}
+	default:
+		specs = append(specs, Specifier{Kind: "project"})
+		for _, match := range csharpUsingPattern.FindAllSubmatch(file.Content, -1) {
+			specs = append(specs, Specifier{Path: string(match[1]), Kind: "using"})
+		}
+	}
+
+	return specs
+}
+
+// IsBuiltin is always false; framework namespaces aren't declared in the project and resolve to nothing
+func (csharpLanguage) IsBuiltin(spec Specifier) bool {
+	return false
+}
+
+// ResolveSpecifier finds the files a project reference or using directive refers to
+func (csharpLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	switch spec.Kind {
+	case "solution":
+		return []string{filepath.Join(filepath.Dir(file.Path), spec.Path)}
+	case "reference":
+		return []string{spec.Path}
+	}
+
+	projectPath := findOwningProject(file.Path, file.ProjectRoot)
+	if projectPath == "" {
+		return nil
+	}
+	if spec.Kind == "project" {
+		return []string{projectPath}
+	}
+
+	// using directives may refer to namespaces declared in this project or any project it references
+	var files []string
+	for _, project := range referencedProjects(projectPath) {
+		files = append(files, project.filesFor(spec.Path)...)
+	}
+	return files
+}
+
+// filesFor returns the files declaring a namespace, or declaring a type for static and alias usings
+func (p *csharpProject) filesFor(name string) []string {
+	if files, ok := p.namespaces[name]; ok {
+		return files
+	}
+
+	// using static Foo.Bar.Baz and using X = Foo.Bar.Baz name a type inside a namespace
+	dot := strings.LastIndex(name, ".")
+	if dot < 0 {
+		return nil
+	}
+	namespace, typeName := name[:dot], name[dot+1:]
+	typePattern := regexp.MustCompile(`\b(?:class|struct|interface|enum|record)\s+` + regexp.QuoteMeta(typeName) + `\b`)
+
+	var files []string
+	for _, path := range p.namespaces[namespace] {
+		if content, err := readFile(path); err == nil && typePattern.Match(content) {
+			files = append(files, path)
+		}
+	}
+	return files
+}
+
+// findOwningProject walks up from a source file to the nearest .csproj
+func findOwningProject(filePath string, projectRoot string) string {
+	dir := filepath.Dir(filePath)
+	for {
+		matches, _ := globFiles(filepath.Join(dir, "*.csproj"))
+		if len(matches) > 0 {
+			return matches[0]
+		}
+		if dir == projectRoot || filepath.Dir(dir) == dir {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// referencedProjects returns a project followed by everything it references, transitively
+func referencedProjects(projectPath string) []*csharpProject {
+	var projects []*csharpProject
+	seen := make(map[string]struct{})
+
+	queue := []string{projectPath}
+	for len(queue) > 0 {
+		path := queue[0]
+		queue = queue[1:]
+		if _, ok := seen[path]; ok {
+			continue
+		}
+		seen[path] = struct{}{}
+
+		if project := loadCSharpProject(path); project != nil {
+			projects = append(projects, project)
+			queue = append(queue, project.references...)
+		}
+	}
+
+	return projects
+}
+
+// loadCSharpProject parses a .csproj file and indexes the namespaces of its sources
+func loadCSharpProject(projectPath string) *csharpProject {
+	if project, ok := cacheLoad(csharpProjects, projectPath); ok {
+		return project
+	}
+
+	// Cache failures too so broken references aren't re-read
+	content, err := readFile(projectPath)
+	if err != nil {
+		cacheStore(csharpProjects, projectPath, nil)
+		return nil
+	}
+	var file csprojFile
+	if err := xml.Unmarshal(content, &file); err != nil {
+		cacheStore(csharpProjects, projectPath, nil)
+		return nil
+	}
+
+	projectDir := filepath.Dir(projectPath)
+	project := &csharpProject{path: projectPath, namespaces: make(map[string][]string)}
+
+	// SDK-style projects compile every .cs file below them unless told otherwise
+	var includes []string
+	if file.Sdk != "" {
+		includes = append(includes, "**/*.cs")
+	}
+	var removes []string
+	for _, group := range file.PropertyGroups {
+		if strings.EqualFold(strings.TrimSpace(group.EnableDefaultCompileItems), "false") {
+			includes = nil
+		}
+	}
+	for _, group := range file.ItemGroups {
+		for _, reference := range group.ProjectReferences {
+			project.references = append(project.references, filepath.Join(projectDir, msbuildPath(reference.Include)))
+		}
+		for _, compile := range group.Compiles {
+			includes = append(includes, splitMSBuildList(compile.Include)...)
+			removes = append(removes, splitMSBuildList(compile.Remove)...)
+		}
+	}
+
+	walkDir(projectDir, func(path string, d os.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		rel, _ := filepath.Rel(projectDir, path)
+		rel = filepath.ToSlash(rel)
+		if d.IsDir() {
+			if rel == "bin" || rel == "obj" || d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if matchesAnyGlob(includes, rel) && !matchesAnyGlob(removes, rel) {
+			project.sources = append(project.sources, path)
+		}
+		return nil
+	})
+
+	for _, source := range project.sources {
+		sourceContent, err := readFile(source)
+		if err != nil {
+			continue
+		}
+		for _, match := range csharpNamespacePattern.FindAllSubmatch(sourceContent, -1) {
+			namespace := string(match[1])
+			project.namespaces[namespace] = append(project.namespaces[namespace], source)
+		}
+	}
+
+	cacheStore(csharpProjects, projectPath, project)
+	return project
+}
+
+// msbuildPath converts an MSBuild path, which uses backslashes, to a native path
+func msbuildPath(path string) string {
+	return filepath.FromSlash(strings.ReplaceAll(path, `\`, "/"))
+}
+
+// splitMSBuildList splits a semicolon separated item list into slash separated globs
+func splitMSBuildList(list string) []string {
+	var items []string
+	for _, item := range strings.Split(list, ";") {
+		if item = strings.TrimSpace(item); item != "" {
+			items = append(items, strings.ReplaceAll(item, `\`, "/"))
+		}
+	}
+	return items
+}
+
+// matchesAnyGlob reports whether a slash separated path matches any of the globs
+func matchesAnyGlob(globs []string, path string) bool {
+	for _, glob := range globs {
+		if matchGlob(strings.Split(glob, "/"), strings.Split(path, "/")) {
+			return true
+		}
+	}
+	return false
+}
+
+// matchGlob matches path segments against glob segments, where ** matches any number of segments
+func matchGlob(glob []string, path []string) bool {
+	for len(glob) > 0 {
+		if glob[0] == "**" {
+			for i := 0; i <= len(path); i++ {
+				if matchGlob(glob[1:], path[i:]) {
+					return true
+				}
+			}
+			return false
+		}
+		if len(path) == 0 {
+			return false
+		}
+		if ok, _ := filepath.Match(glob[0], path[0]); !ok {
+			return false
+		}
+		glob, path = glob[1:], path[1:]
+	}
+	return len(path) == 0
+}
@@ -0,0 +1,111 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtractCSharpImports tests namespace resolution across project references
+func TestExtractCSharpImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "csharp-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"Shop.sln": `
+Project("{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}") = "App", "App\App.csproj", "{11111111-1111-1111-1111-111111111111}"
+EndProject
+Project("{FAE04EC0-301F-11D3-BF4B-00C04F79EFBC}") = "Core", "Core\Core.csproj", "{22222222-2222-2222-2222-222222222222}"
+EndProject
+`,
+		"App/App.csproj": `<Project Sdk="Microsoft.NET.Sdk">
+  <ItemGroup>
+    <ProjectReference Include="..\Core\Core.csproj" />
+  </ItemGroup>
+</Project>`,
+		"App/Program.cs": "",
+		"Core/Core.csproj": `<Project Sdk="Microsoft.NET.Sdk">
+  <ItemGroup>
+    <Compile Remove="Legacy\**" />
+  </ItemGroup>
+</Project>`,
+		"Core/Models/User.cs":   "namespace Core.Models;\npublic class User {}",
+		"Core/Models/Order.cs":  "namespace Core.Models\n{\n  public class Order {}\n}",
+		"Core/Util/Helpers.cs":  "namespace Core.Util;\npublic static class Helpers {}",
+		"Core/Util/Other.cs":    "namespace Core.Util;\npublic class Other {}",
+		"Core/Legacy/OldApi.cs": "namespace Core.Models;\npublic class OldApi {}",
+		"Core/obj/Generated.cs": "namespace Core.Models;\npublic class Generated {}",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	programPath := filepath.Join(tempDir, "App", "Program.cs")
+	program := []byte(`
+using System;
+using Core.Models;
+using static Core.Util.Helpers;
+
+Console.WriteLine(new User());
+`)
+
+	imports := extractImportsFromContent(programPath, ".cs", program, tempDir)
+	expected := []string{
+		filepath.Join(tempDir, "App", "App.csproj"),
+		filepath.Join(tempDir, "Core", "Models", "User.cs"),
+		filepath.Join(tempDir, "Core", "Models", "Order.cs"),
+		filepath.Join(tempDir, "Core", "Util", "Helpers.cs"),
+	}
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for _, path := range expected {
+		if !containsString(imports, path) {
+			t.Errorf("Expected to find %s in imports", path)
+		}
+	}
+
+	// Project files pull in their references and solutions their projects
+	appProject := filepath.Join(tempDir, "App", "App.csproj")
+	coreProject := filepath.Join(tempDir, "Core", "Core.csproj")
+	if imports := extractImportsFromContent(appProject, ".csproj", nil, tempDir); len(imports) != 1 || imports[0] != coreProject {
+		t.Errorf("Expected App.csproj to reference %s, got %v", coreProject, imports)
+	}
+
+	slnPath := filepath.Join(tempDir, "Shop.sln")
+	sln, _ := os.ReadFile(slnPath)
+	if imports := extractImportsFromContent(slnPath, ".sln", sln, tempDir); len(imports) != 2 {
+		t.Errorf("Expected 2 projects in the solution, got %v", imports)
+	}
+}
+
+// TestMatchGlob tests MSBuild-style glob matching
+func TestMatchGlob(t *testing.T) {
+	testCases := []struct {
+		glob     string
+		path     string
+		expected bool
+	}{
+		{"**/*.cs", "Program.cs", true},
+		{"**/*.cs", "Models/User.cs", true},
+		{"Legacy/**", "Legacy/Old/Api.cs", true},
+		{"Models/*.cs", "Models/Sub/User.cs", false},
+		{"**/*.cs", "README.md", false},
+	}
+
+	for _, tc := range testCases {
+		if got := matchesAnyGlob([]string{tc.glob}, tc.path); got != tc.expected {
+			t.Errorf("matchesAnyGlob(%q, %q) = %v, want %v", tc.glob, tc.path, got, tc.expected)
+		}
+	}
+}
@@ -0,0 +1,403 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"sort"
+	"strings"
+)
+
+// Lines of unchanged context around each change in a unified diff
+const diffContext = 3
+
+// BundleDiff is the difference between two bundles
+type BundleDiff struct {
+	// Added and Removed are files that joined or left the dependency closure
+	Added   []string `json:"added"`
+	Removed []string `json:"removed"`
+	// Changed are files in both bundles whose contents differ
+	Changed []FileChange `json:"changed"`
+	// AddedImports and RemovedImports are edges of the import graph that appeared or disappeared
+	AddedImports   []ImportEdge `json:"addedImports"`
+	RemovedImports []ImportEdge `json:"removedImports"`
+}
+
+// FileChange is a file whose contents differ between two bundles
+type FileChange struct {
+	Path string `json:"path"`
+	Diff string `json:"diff"`
+}
+
+// ImportEdge is one file importing another
+type ImportEdge struct {
+	From string `json:"from"`
+	To   string `json:"to"`
+}
+
+// DiffBundles compares two bundles of the same project
+func DiffBundles(old *Bundle, new *Bundle) *BundleDiff {
+	// Empty lists rather than nil, so they're encoded as [] in JSON
+	diff := &BundleDiff{Added: []string{}, Removed: []string{}, Changed: []FileChange{}, AddedImports: []ImportEdge{}, RemovedImports: []ImportEdge{}}
+
+	for _, file := range new.Files {
+		oldFile := old.file(file.Path)
+		if oldFile == nil {
+			diff.Added = append(diff.Added, file.Path)
+		} else if oldFile.Content != file.Content {
+			diff.Changed = append(diff.Changed, FileChange{Path: file.Path, Diff: unifiedDiff(file.Path, oldFile.Content, file.Content)})
+		}
+	}
+	for _, file := range old.Files {
+		if new.file(file.Path) == nil {
+			diff.Removed = append(diff.Removed, file.Path)
+		}
+	}
+
+	oldEdges := importEdges(old)
+	newEdges := importEdges(new)
+	for edge := range newEdges {
+		if _, ok := oldEdges[edge]; !ok {
+			diff.AddedImports = append(diff.AddedImports, edge)
+		}
+	}
+	for edge := range oldEdges {
+		if _, ok := newEdges[edge]; !ok {
+			diff.RemovedImports = append(diff.RemovedImports, edge)
+		}
+	}
+	sortEdges(diff.AddedImports)
+	sortEdges(diff.RemovedImports)
+
+	return diff
+}
+
+// importEdges returns the set of imports between the files of a bundle
+func importEdges(bundle *Bundle) map[ImportEdge]struct{} {
+	edges := make(map[ImportEdge]struct{})
+	for _, file := range bundle.Files {
+		for _, imported := range file.Imports {
+			edges[ImportEdge{From: file.Path, To: imported}] = struct{}{}
+		}
+	}
+	return edges
+}
+
+// sortEdges orders import edges by importing file, then imported file
+func sortEdges(edges []ImportEdge) {
+	sort.Slice(edges, func(i, j int) bool {
+		if edges[i].From != edges[j].From {
+			return edges[i].From < edges[j].From
+		}
+		return edges[i].To < edges[j].To
+	})
+}
+
+// Empty reports whether the bundles were the same
+func (d *BundleDiff) Empty() bool {
+	return len(d.Added) == 0 && len(d.Removed) == 0 && len(d.Changed) == 0 &&
+		len(d.AddedImports) == 0 && len(d.RemovedImports) == 0
+}
+
+// FormatBundleDiff formats a bundle comparison for output
+func FormatBundleDiff(diff *BundleDiff) string {
+	var builder strings.Builder
+
+	section := func(title string, count int) {
+		fmt.Fprintf(&builder, "------------------------------\n")
+		fmt.Fprintf(&builder, "%s (%d)\n", title, count)
+		fmt.Fprintf(&builder, "------------------------------\n\n")
+	}
+
+	if len(diff.Added) > 0 {
+		section("Added files", len(diff.Added))
+		for _, path := range diff.Added {
+			fmt.Fprintf(&builder, "+ %s\n", path)
+		}
+		fmt.Fprint(&builder, "\n")
+	}
+	if len(diff.Removed) > 0 {
+		section("Removed files", len(diff.Removed))
+		for _, path := range diff.Removed {
+			fmt.Fprintf(&builder, "- %s\n", path)
+		}
+		fmt.Fprint(&builder, "\n")
+	}
+	if len(diff.AddedImports) > 0 || len(diff.RemovedImports) > 0 {
+		section("Import changes", len(diff.AddedImports)+len(diff.RemovedImports))
+		for _, edge := range diff.AddedImports {
+			fmt.Fprintf(&builder, "+ %s -> %s\n", edge.From, edge.To)
+		}
+		for _, edge := range diff.RemovedImports {
+			fmt.Fprintf(&builder, "- %s -> %s\n", edge.From, edge.To)
+		}
+		fmt.Fprint(&builder, "\n")
+	}
+	if len(diff.Changed) > 0 {
+		section("Changed files", len(diff.Changed))
+		for _, change := range diff.Changed {
+			fmt.Fprintf(&builder, "{{ BEGIN DIFF OF %s }}\n", change.Path)
+			fmt.Fprint(&builder, change.Diff)
+			fmt.Fprintf(&builder, "{{ END DIFF OF %s }}\n\n", change.Path)
+		}
+	}
+
+	fmt.Fprintf(&builder, "------------------------------\n")
+	if diff.Empty() {
+		fmt.Fprintf(&builder, "No differences\n")
+	} else {
+		fmt.Fprintf(&builder, "%d added, %d removed and %d changed files; %d imports added and %d removed\n",
+			len(diff.Added), len(diff.Removed), len(diff.Changed), len(diff.AddedImports), len(diff.RemovedImports))
+	}
+
+	return builder.String()
+}
+
+// isBundleFile reports whether a diff argument names a saved bundle rather than a revision
+func isBundleFile(arg string) bool {
+	return strings.HasSuffix(strings.ToLower(arg), ".json")
+}
+
+// bundleFor loads a saved bundle, or collects the entry file at a revision
+func bundleFor(arg string, entryPath string, projectRoot string) (*Bundle, error) {
+	if isBundleFile(arg) {
+		return LoadBundle(arg)
+	}
+	repoRoot, err := gitRepoRootFor(entryPath)
+	if err != nil {
+		return nil, fmt.Errorf("could not find git repository: %v", err)
+	}
+	return CollectAtRevision(repoRoot, arg, entryPath, projectRoot)
+}
+
+// PrintBundleDiff compares two bundles, each saved or collected at a revision, and prints the result
+func PrintBundleDiff(oldArg string, newArg string, entryPath string, projectRoot string, format string) error {
+	old, err := bundleFor(oldArg, entryPath, projectRoot)
+	if err != nil {
+		return err
+	}
+	new, err := bundleFor(newArg, entryPath, projectRoot)
+	if err != nil {
+		return err
+	}
+
+	diff := DiffBundles(old, new)
+	if format == "json" {
+		content, err := json.MarshalIndent(diff, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	}
+	fmt.Print(FormatBundleDiff(diff))
+	return nil
+}
+
+// CollectAtRevision collects the files an entry file depends on as they were at a git revision
+func CollectAtRevision(repoRoot string, rev string, entryPath string, projectRoot string) (*Bundle, error) {
+	revFS, err := newGitRevFS(repoRoot, rev)
+	if err != nil {
+		return nil, err
+	}
+	defer revFS.Close()
+
+	defer func(original fs.FS) { fileSystem = original }(fileSystem)
+	fileSystem = newMountFS(repoRoot, revFS, fileSystem)
+	resetCollection()
+
+	results := make(map[string]string)
+	if err := ProcessFile(entryPath, projectRoot, results); err != nil {
+		return nil, fmt.Errorf("at %s: %v", rev, err)
+	}
+	bundle := NewBundle(projectRoot, []string{entryPath}, results)
+	bundle.Revision = rev
+	return bundle, nil
+}
+
+// diffOp is a line of an edit script: kept (' '), removed ('-') or added ('+')
+type diffOp struct {
+	kind byte
+	line string
+}
+
+// unifiedDiff returns the changes between two versions of a file in unified diff format
+func unifiedDiff(path string, old string, new string) string {
+	ops := diffLines(splitLines(old), splitLines(new))
+
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "--- a/%s\n+++ b/%s\n", path, path)
+
+	// Line numbers in the old and new file before each op
+	oldLines := make([]int, len(ops)+1)
+	newLines := make([]int, len(ops)+1)
+	for i, op := range ops {
+		oldLines[i+1], newLines[i+1] = oldLines[i], newLines[i]
+		if op.kind != '+' {
+			oldLines[i+1]++
+		}
+		if op.kind != '-' {
+			newLines[i+1]++
+		}
+	}
+
+	for i := 0; i < len(ops); {
+		if ops[i].kind == ' ' {
+			i++
+			continue
+		}
+
+		// Changes closer together than twice the context share a hunk
+		start := max(0, i-diffContext)
+		last := i
+		for j := i + 1; j < len(ops) && j <= last+2*diffContext+1; j++ {
+			if ops[j].kind != ' ' {
+				last = j
+			}
+		}
+		end := min(len(ops), last+diffContext+1)
+
+		fmt.Fprintf(&builder, "@@ -%s +%s @@\n",
+			hunkRange(oldLines[start], oldLines[end]-oldLines[start]),
+			hunkRange(newLines[start], newLines[end]-newLines[start]))
+		for _, op := range ops[start:end] {
+			fmt.Fprintf(&builder, "%c%s\n", op.kind, op.line)
+		}
+		i = end
+	}
+
+	return builder.String()
+}
+
+// hunkRange formats the start and length of a hunk, where an empty range names the line before it
+func hunkRange(before int, length int) string {
+	if length == 0 {
+		return fmt.Sprintf("%d,0", before)
+	}
+	if length == 1 {
+		return fmt.Sprintf("%d", before+1)
+	}
+	return fmt.Sprintf("%d,%d", before+1, length)
+}
+
+// noNewlineMarker follows a last line without a newline, so it differs from the same line with one
+// and is printed with the marker diff and patch expect
+const noNewlineMarker = "\n\\ No newline at end of file"
+
+// splitLines splits content into lines. A final line without a newline ends with noNewlineMarker.
+func splitLines(content string) []string {
+	if content == "" {
+		return nil
+	}
+	if !strings.HasSuffix(content, "\n") {
+		lines := strings.Split(content, "\n")
+		lines[len(lines)-1] += noNewlineMarker
+		return lines
+	}
+	return strings.Split(strings.TrimSuffix(content, "\n"), "\n")
+}
+
+// diffLines returns the shortest edit script turning a into b, using the linear space variant of
+// Myers' algorithm so memory stays proportional to the length of the files however much differs
+func diffLines(a []string, b []string) []diffOp {
+	var ops []diffOp
+	diffRange(a, b, &ops)
+	return ops
+}
+
+// diffRange appends the edit script turning a into b to ops, splitting it at the middle snake of
+// an optimal path and diffing either side
+func diffRange(a []string, b []string, ops *[]diffOp) {
+	// Lines in common at either end are kept as they are
+	for len(a) > 0 && len(b) > 0 && a[0] == b[0] {
+		*ops = append(*ops, diffOp{' ', a[0]})
+		a, b = a[1:], b[1:]
+	}
+	suffix := 0
+	for suffix < len(a) && suffix < len(b) && a[len(a)-1-suffix] == b[len(b)-1-suffix] {
+		suffix++
+	}
+	common := a[len(a)-suffix:]
+	a, b = a[:len(a)-suffix], b[:len(b)-suffix]
+
+	switch {
+	case len(a) == 0:
+		for _, line := range b {
+			*ops = append(*ops, diffOp{'+', line})
+		}
+	case len(b) == 0:
+		for _, line := range a {
+			*ops = append(*ops, diffOp{'-', line})
+		}
+	default:
+		// Both sides differ at their first and last lines, so there are at least two edits and
+		// each half has fewer than the whole
+		x, y, u, v := middleSnake(a, b)
+		diffRange(a[:x], b[:y], ops)
+		for _, line := range a[x:u] {
+			*ops = append(*ops, diffOp{' ', line})
+		}
+		diffRange(a[u:], b[v:], ops)
+	}
+
+	for _, line := range common {
+		*ops = append(*ops, diffOp{' ', line})
+	}
+}
+
+// middleSnake finds the snake in the middle of a shortest edit script turning a into b by
+// searching forwards from the start and backwards from the end until the paths overlap. The
+// snake runs from (x, y) to (u, v).
+func middleSnake(a []string, b []string) (x, y, u, v int) {
+	n, m := len(a), len(b)
+	delta := n - m
+	odd := delta%2 != 0
+	limit := (n + m + 1) / 2
+	offset := limit + 1
+
+	// Furthest reaching x on each diagonal k = x - y, forwards and backwards. Backward diagonals
+	// are numbered from the end of both sides, so backward diagonal c meets forward diagonal delta - c.
+	forward := make([]int, 2*offset+1)
+	backward := make([]int, 2*offset+1)
+	for d := 0; d <= limit; d++ {
+		for k := -d; k <= d; k += 2 {
+			if k == -d || (k != d && forward[offset+k-1] < forward[offset+k+1]) {
+				x = forward[offset+k+1]
+			} else {
+				x = forward[offset+k-1] + 1
+			}
+			y = x - k
+			u, v = x, y
+			for u < n && v < m && a[u] == b[v] {
+				u++
+				v++
+			}
+			forward[offset+k] = u
+			if c := delta - k; odd && c >= -(d-1) && c <= d-1 && u+backward[offset+c] >= n {
+				return x, y, u, v
+			}
+		}
+
+		for c := -d; c <= d; c += 2 {
+			var bx int
+			if c == -d || (c != d && backward[offset+c-1] < backward[offset+c+1]) {
+				bx = backward[offset+c+1]
+			} else {
+				bx = backward[offset+c-1] + 1
+			}
+			by := bx - c
+			startX, startY := bx, by
+			for bx < n && by < m && a[n-1-bx] == b[m-1-by] {
+				bx++
+				by++
+			}
+			backward[offset+c] = bx
+			if k := delta - c; !odd && k >= -d && k <= d && bx+forward[offset+k] >= n {
+				return n - bx, m - by, n - startX, m - startY
+			}
+		}
+	}
+
+	// Unreachable, as the paths always meet by the time half of the edits are made from each end
+	return 0, 0, 0, 0
+}
@@ -0,0 +1,296 @@
+package main
+
+import (
+	"fmt"
+	"io/fs"
+	"math/rand"
+	"os"
+	"path/filepath"
+	"runtime"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestUnifiedDiff tests formatting the changes between two versions of a file
+func TestUnifiedDiff(t *testing.T) {
+	tests := []struct {
+		name     string
+		old      string
+		new      string
+		expected string
+	}{
+		{
+			name:     "identical",
+			old:      "a\nb\n",
+			new:      "a\nb\n",
+			expected: "",
+		},
+		{
+			name:     "new file",
+			old:      "",
+			new:      "a\nb\n",
+			expected: "@@ -0,0 +1,2 @@\n+a\n+b\n",
+		},
+		{
+			name:     "changed line with context",
+			old:      "1\n2\n3\n4\n5\n6\n7\n8\n9\n",
+			new:      "1\n2\n3\n4\nfive\n6\n7\n8\n9\n",
+			expected: "@@ -2,7 +2,7 @@\n 2\n 3\n 4\n-5\n+five\n 6\n 7\n 8\n",
+		},
+		{
+			name:     "distant changes in separate hunks",
+			old:      "a\n1\n2\n3\n4\n5\n6\n7\n8\nb\n",
+			new:      "A\n1\n2\n3\n4\n5\n6\n7\n8\nB\n",
+			expected: "@@ -1,4 +1,4 @@\n-a\n+A\n 1\n 2\n 3\n@@ -7,4 +7,4 @@\n 6\n 7\n 8\n-b\n+B\n",
+		},
+		{
+			name:     "nearby changes in one hunk",
+			old:      "a\n1\n2\n3\n4\n5\n6\nb\n",
+			new:      "A\n1\n2\n3\n4\n5\n6\nB\n",
+			expected: "@@ -1,8 +1,8 @@\n-a\n+A\n 1\n 2\n 3\n 4\n 5\n 6\n-b\n+B\n",
+		},
+		{
+			name:     "only the trailing newline added",
+			old:      "a\nb",
+			new:      "a\nb\n",
+			expected: "@@ -1,2 +1,2 @@\n a\n-b\n\\ No newline at end of file\n+b\n",
+		},
+		{
+			name:     "only the trailing newline removed",
+			old:      "a\nb\n",
+			new:      "a\nb",
+			expected: "@@ -1,2 +1,2 @@\n a\n-b\n+b\n\\ No newline at end of file\n",
+		},
+		{
+			name:     "changed line before a last line without a newline",
+			old:      "a\nb",
+			new:      "A\nb",
+			expected: "@@ -1,2 +1,2 @@\n-a\n+A\n b\n\\ No newline at end of file\n",
+		},
+		{
+			name:     "removed line",
+			old:      "a\nb\nc\n",
+			new:      "a\nc\n",
+			expected: "@@ -1,3 +1,2 @@\n a\n-b\n c\n",
+		},
+	}
+
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			diff := unifiedDiff("file.txt", test.old, test.new)
+			header := "--- a/file.txt\n+++ b/file.txt\n"
+			if !strings.HasPrefix(diff, header) {
+				t.Fatalf("Expected diff to start with file headers, got %q", diff)
+			}
+			if hunks := strings.TrimPrefix(diff, header); hunks != test.expected {
+				t.Errorf("Expected hunks:\n%s\ngot:\n%s", test.expected, hunks)
+			}
+		})
+	}
+}
+
+// TestDiffBundles tests comparing the files and imports of two bundles
+func TestDiffBundles(t *testing.T) {
+	defer func(original fs.FS) { fileSystem = original }(fileSystem)
+	root := filepath.Join(string(filepath.Separator), "project")
+	collect := func(files fstest.MapFS) *Bundle {
+		fileSystem = files
+		resetCollection()
+		results := make(map[string]string)
+		entry := filepath.Join(root, "src", "app.js")
+		if err := ProcessFile(entry, root, results); err != nil {
+			t.Fatalf("ProcessFile failed: %v", err)
+		}
+		return NewBundle(root, []string{entry}, results)
+	}
+
+	old := collect(fstest.MapFS{
+		"project/package.json":   {Data: []byte(`{"name": "app"}`)},
+		"project/src/app.js":     {Data: []byte("import { a } from './a';\nimport { b } from './b';\n")},
+		"project/src/a.js":       {Data: []byte("import { c } from './c';\nexport const a = 1;\n")},
+		"project/src/b.js":       {Data: []byte("export const b = 1;\n")},
+		"project/src/c/index.js": {Data: []byte("export const c = 1;\n")},
+	})
+	new := collect(fstest.MapFS{
+		"project/package.json":   {Data: []byte(`{"name": "app"}`)},
+		"project/src/app.js":     {Data: []byte("import { a } from './a';\nimport { b } from './b';\n")},
+		"project/src/a.js":       {Data: []byte("import { d } from './d';\nexport const a = 2;\n")},
+		"project/src/b.js":       {Data: []byte("import { d } from './d';\nexport const b = 1;\n")},
+		"project/src/c/index.js": {Data: []byte("export const c = 1;\n")},
+		"project/src/d.js":       {Data: []byte("export const d = 1;\n")},
+	})
+
+	// Imports are recorded relative to the project root, including index files
+	if a := old.file("src/a.js"); a == nil || strings.Join(a.Imports, ",") != "src/c/index.js" {
+		t.Errorf("Expected src/a.js to import src/c/index.js, got %v", a)
+	}
+
+	diff := DiffBundles(old, new)
+	if strings.Join(diff.Added, ",") != "src/d.js" {
+		t.Errorf("Expected src/d.js to be added, got %v", diff.Added)
+	}
+	if strings.Join(diff.Removed, ",") != "src/c/index.js" {
+		t.Errorf("Expected src/c/index.js to be removed, got %v", diff.Removed)
+	}
+	if len(diff.Changed) != 2 || diff.Changed[0].Path != "src/a.js" || diff.Changed[1].Path != "src/b.js" {
+		t.Fatalf("Expected src/a.js and src/b.js to change, got %v", diff.Changed)
+	}
+	if !strings.Contains(diff.Changed[0].Diff, "+export const a = 2;") {
+		t.Errorf("Expected the diff of src/a.js, got %q", diff.Changed[0].Diff)
+	}
+
+	expectedAdded := []ImportEdge{{"src/a.js", "src/d.js"}, {"src/b.js", "src/d.js"}}
+	if len(diff.AddedImports) != len(expectedAdded) {
+		t.Fatalf("Expected imports %v to be added, got %v", expectedAdded, diff.AddedImports)
+	}
+	for i, edge := range expectedAdded {
+		if diff.AddedImports[i] != edge {
+			t.Errorf("Expected added import %v, got %v", edge, diff.AddedImports[i])
+		}
+	}
+	if len(diff.RemovedImports) != 1 || diff.RemovedImports[0] != (ImportEdge{"src/a.js", "src/c/index.js"}) {
+		t.Errorf("Expected the import of src/c/index.js to be removed, got %v", diff.RemovedImports)
+	}
+
+	output := FormatBundleDiff(diff)
+	for _, expected := range []string{"+ src/d.js", "- src/c/index.js", "+ src/b.js -> src/d.js", "{{ BEGIN DIFF OF src/a.js }}"} {
+		if !strings.Contains(output, expected) {
+			t.Errorf("Expected output to contain %q", expected)
+		}
+	}
+
+	if !DiffBundles(old, old).Empty() {
+		t.Errorf("Expected no differences between a bundle and itself")
+	}
+}
+
+// TestBundleRoundTrip tests saving a bundle and comparing it with one collected at a revision
+func TestBundleRoundTrip(t *testing.T) {
+	tempDir, git, write := newTestRepo(t)
+	defer resetCollection()
+
+	write("go.mod", "module example.com/app\n")
+	write("main.go", "package main\n\nimport \"example.com/app/util\"\n")
+	write("util/util.go", "package util\n\nconst Version = 1\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "v1")
+	git("tag", "v1")
+	write("util/util.go", "package util\n\nconst Version = 2\n")
+	git("commit", "-q", "-am", "v2")
+
+	entry := filepath.Join(tempDir, "main.go")
+	old, err := CollectAtRevision(tempDir, "v1", entry, tempDir)
+	if err != nil {
+		t.Fatalf("CollectAtRevision failed: %v", err)
+	}
+	if old.Revision != "v1" || len(old.Files) != 2 {
+		t.Fatalf("Expected 2 files at v1, got %+v", old)
+	}
+
+	// Save the old bundle and load it back
+	content, err := old.JSON()
+	if err != nil {
+		t.Fatalf("JSON failed: %v", err)
+	}
+	saved := filepath.Join(tempDir, "old.json")
+	if err := os.WriteFile(saved, []byte(content), 0644); err != nil {
+		t.Fatalf("Failed to save bundle: %v", err)
+	}
+	loaded, err := bundleFor(saved, entry, tempDir)
+	if err != nil {
+		t.Fatalf("Failed to load bundle: %v", err)
+	}
+
+	new, err := bundleFor("HEAD", entry, tempDir)
+	if err != nil {
+		t.Fatalf("Failed to collect HEAD: %v", err)
+	}
+	diff := DiffBundles(loaded, new)
+	if len(diff.Changed) != 1 || diff.Changed[0].Path != "util/util.go" {
+		t.Fatalf("Expected util/util.go to change, got %+v", diff)
+	}
+	if !strings.Contains(diff.Changed[0].Diff, "-const Version = 1\n+const Version = 2\n") {
+		t.Errorf("Unexpected diff of util/util.go: %q", diff.Changed[0].Diff)
+	}
+	if len(diff.Added) != 0 || len(diff.Removed) != 0 || len(diff.AddedImports) != 0 || len(diff.RemovedImports) != 0 {
+		t.Errorf("Expected only a content change, got %+v", diff)
+	}
+}
+
+// TestDiffLinesMinimal tests that edit scripts turn one side into the other with the fewest edits
+func TestDiffLinesMinimal(t *testing.T) {
+	random := rand.New(rand.NewSource(1))
+	randomLines := func() []string {
+		lines := make([]string, random.Intn(12))
+		for i := range lines {
+			lines[i] = string(rune('a' + random.Intn(3)))
+		}
+		return lines
+	}
+
+	for i := 0; i < 2000; i++ {
+		a, b := randomLines(), randomLines()
+		ops := diffLines(a, b)
+
+		var fromA, fromB []string
+		edits := 0
+		for _, op := range ops {
+			if op.kind != '+' {
+				fromA = append(fromA, op.line)
+			}
+			if op.kind != '-' {
+				fromB = append(fromB, op.line)
+			}
+			if op.kind != ' ' {
+				edits++
+			}
+		}
+		if strings.Join(fromA, "") != strings.Join(a, "") || strings.Join(fromB, "") != strings.Join(b, "") {
+			t.Fatalf("Edit script for %v -> %v doesn't reproduce both sides: %v", a, b, ops)
+		}
+		if expected := len(a) + len(b) - 2*longestCommonSubsequence(a, b); edits != expected {
+			t.Fatalf("Expected %d edits for %v -> %v, got %d: %v", expected, a, b, edits, ops)
+		}
+	}
+}
+
+// longestCommonSubsequence returns the length of the longest common subsequence of two lists of lines
+func longestCommonSubsequence(a []string, b []string) int {
+	lengths := make([][]int, len(a)+1)
+	for i := range lengths {
+		lengths[i] = make([]int, len(b)+1)
+	}
+	for i := len(a) - 1; i >= 0; i-- {
+		for j := len(b) - 1; j >= 0; j-- {
+			if a[i] == b[j] {
+				lengths[i][j] = lengths[i+1][j+1] + 1
+			} else {
+				lengths[i][j] = max(lengths[i+1][j], lengths[i][j+1])
+			}
+		}
+	}
+	return lengths[0][0]
+}
+
+// TestDiffLinesRewrite tests that diffing a completely rewritten file doesn't need memory for every round of edits
+func TestDiffLinesRewrite(t *testing.T) {
+	a := make([]string, 10000)
+	b := make([]string, 10000)
+	for i := range a {
+		a[i] = fmt.Sprintf("old %d", i)
+		b[i] = fmt.Sprintf("new %d", i)
+	}
+
+	var before, after runtime.MemStats
+	runtime.ReadMemStats(&before)
+	ops := diffLines(a, b)
+	runtime.ReadMemStats(&after)
+
+	if len(ops) != 20000 {
+		t.Errorf("Expected 20000 edits, got %d", len(ops))
+	}
+	if allocated := after.TotalAlloc - before.TotalAlloc; allocated > 64<<20 {
+		t.Errorf("Expected diffing 10000 lines to allocate less than 64MB, allocated %dMB", allocated>>20)
+	}
+}
@@ -0,0 +1,146 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+// Files from third-party packages that were included, mapped to their package name
+var externalFiles = make(map[string]string)
+
+// followsExternal reports whether any third-party sources may be followed
+func followsExternal() bool {
+	return len(config.IncludeExternal) > 0 || config.IncludeExternalDepth > 0
+}
+
+// externalAllowed reports whether the sources of a third-party package may be followed.
+// Without an explicit package list, any package is allowed up to the configured depth.
+func externalAllowed(name string) bool {
+	if !followsExternal() {
+		return false
+	}
+	if len(config.IncludeExternal) == 0 {
+		return true
+	}
+	for _, pkg := range config.IncludeExternal {
+		// Go modules and Python packages are also matched by prefix, e.g. golang.org/x/net for golang.org/x/net/html
+		if name == pkg || strings.HasPrefix(name, pkg+"/") || strings.HasPrefix(name, pkg+".") {
+			return true
+		}
+	}
+	return false
+}
+
+// externalDepthLimit returns how many imports may be followed into third-party code
+func externalDepthLimit() int {
+	if config.IncludeExternalDepth > 0 {
+		return config.IncludeExternalDepth
+	}
+	return 1
+}
+
+// externalPackageOf returns the third-party package a file belongs to, or "" for project files
+func externalPackageOf(path string, projectRoot string) string {
+	sep := string(filepath.Separator)
+	segments := strings.Split(filepath.ToSlash(path), "/")
+
+	for i, segment := range segments {
+		switch segment {
+		case "node_modules":
+			if i+1 < len(segments) {
+				if strings.HasPrefix(segments[i+1], "@") && i+2 < len(segments) {
+					return segments[i+1] + "/" + segments[i+2]
+				}
+				return segments[i+1]
+			}
+		case "site-packages":
+			if i+1 < len(segments) {
+				return strings.TrimSuffix(segments[i+1], ".py")
+			}
+		}
+	}
+
+	if modCache := goModCache(); modCache != "" && strings.HasPrefix(path, modCache+sep) {
+		return goModulePathFromCache(strings.TrimPrefix(path, modCache+sep))
+	}
+
+	vendorDir := filepath.Join(projectRoot, "vendor") + sep
+	if strings.HasPrefix(path, vendorDir) {
+		parts := strings.Split(filepath.ToSlash(strings.TrimPrefix(path, vendorDir)), "/")
+		// Go vendors by module path (github.com/org/repo), PHP by vendor/package
+		n := 2
+		if strings.Contains(parts[0], ".") {
+			n = 3
+		}
+		if len(parts) > n {
+			return strings.Join(parts[:n], "/")
+		}
+		if len(parts) > 1 {
+			return strings.Join(parts[:len(parts)-1], "/")
+		}
+		return "vendor"
+	}
+
+	return ""
+}
+
+// goModCache returns the Go module cache directory
+func goModCache() string {
+	if dir := os.Getenv("GOMODCACHE"); dir != "" {
+		return filepath.Clean(dir)
+	}
+	if gopath := os.Getenv("GOPATH"); gopath != "" {
+		return filepath.Join(filepath.SplitList(gopath)[0], "pkg", "mod")
+	}
+	if home, err := os.UserHomeDir(); err == nil {
+		return filepath.Join(home, "go", "pkg", "mod")
+	}
+	return ""
+}
+
+// goModulePathFromCache turns a path below the module cache, like github.com/!burnt!sushi/toml@v1.3.2/decode.go,
+// into its module path
+func goModulePathFromCache(rel string) string {
+	var parts []string
+	for _, segment := range strings.Split(filepath.ToSlash(rel), "/") {
+		if at := strings.Index(segment, "@"); at >= 0 {
+			parts = append(parts, segment[:at])
+			break
+		}
+		parts = append(parts, segment)
+	}
+	return unescapeGoModulePath(strings.Join(parts, "/"))
+}
+
+// escapeGoModulePath applies the module cache's case encoding, where upper case letters become !lower
+func escapeGoModulePath(path string) string {
+	var builder strings.Builder
+	for _, r := range path {
+		if r >= 'A' && r <= 'Z' {
+			builder.WriteByte('!')
+			builder.WriteRune(r + ('a' - 'A'))
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+	return builder.String()
+}
+
+// unescapeGoModulePath reverses escapeGoModulePath
+func unescapeGoModulePath(path string) string {
+	var builder strings.Builder
+	upper := false
+	for _, r := range path {
+		if r == '!' {
+			upper = true
+			continue
+		}
+		if upper && r >= 'a' && r <= 'z' {
+			r -= 'a' - 'A'
+		}
+		upper = false
+		builder.WriteRune(r)
+	}
+	return builder.String()
+}
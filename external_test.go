@@ -0,0 +1,148 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestIncludeExternal tests following imports into node_modules, site-packages and the Go module cache
+func TestIncludeExternal(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "include-external-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	modCache := filepath.Join(tempDir, "gomodcache")
+	t.Setenv("GOMODCACHE", modCache)
+	t.Setenv("VIRTUAL_ENV", "")
+
+	projectDir := filepath.Join(tempDir, "project")
+	files := map[string]string{
+		"project/package.json":                                            `{"dependencies": {"lodash": "^4.0.0", "left-pad": "^1.0.0"}}`,
+		"project/web/app.js":                                              "import debounce from 'lodash';\nimport pad from 'left-pad';\n",
+		"project/node_modules/lodash/package.json":                        `{"name": "lodash", "main": "index.js"}`,
+		"project/node_modules/lodash/index.js":                            "module.exports = require('./debounce');",
+		"project/node_modules/lodash/debounce.js":                         "module.exports = function debounce() {};",
+		"project/node_modules/left-pad/package.json":                      `{"name": "left-pad", "main": "index.js"}`,
+		"project/node_modules/left-pad/index.js":                          "",
+		"project/go.mod":                                                  "module example.com/app\n\nrequire github.com/BurntSushi/toml v1.3.2\n",
+		"project/main.go":                                                 "package main\n\nimport (\n\t\"fmt\"\n\t\"github.com/BurntSushi/toml\"\n)\n",
+		"gomodcache/github.com/!burnt!sushi/toml@v1.3.2/decode.go":        "package toml",
+		"project/app.py":                                                  "import os\nimport requests\n",
+		"project/.venv/lib/python3.12/site-packages/requests/__init__.py": "from . import api",
+		"project/.venv/lib/python3.12/site-packages/requests/api.py":      "",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		entry    string
+		config   Config
+		expected []string
+	}{
+		{
+			name:     "Third-party code is skipped by default",
+			entry:    "web/app.js",
+			config:   Config{},
+			expected: []string{"web/app.js"},
+		},
+		{
+			name:     "Named npm package at depth 1",
+			entry:    "web/app.js",
+			config:   Config{IncludeExternal: []string{"lodash"}},
+			expected: []string{"web/app.js", "node_modules/lodash/index.js"},
+		},
+		{
+			name:     "Named npm package at depth 2",
+			entry:    "web/app.js",
+			config:   Config{IncludeExternal: []string{"lodash"}, IncludeExternalDepth: 2},
+			expected: []string{"web/app.js", "node_modules/lodash/index.js", "node_modules/lodash/debounce.js"},
+		},
+		{
+			name:     "Go module cache",
+			entry:    "main.go",
+			config:   Config{IncludeExternal: []string{"github.com/BurntSushi/toml"}},
+			expected: []string{"main.go", "../gomodcache/github.com/!burnt!sushi/toml@v1.3.2/decode.go"},
+		},
+		{
+			name:     "Python site-packages",
+			entry:    "app.py",
+			config:   Config{IncludeExternalDepth: 1},
+			expected: []string{"app.py", ".venv/lib/python3.12/site-packages/requests/__init__.py"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config = tc.config
+			defer func() { config = Config{} }()
+			processedFiles = make(map[string]struct{})
+			externalFiles = make(map[string]string)
+
+			results := make(map[string]string)
+			if err := ProcessFile(filepath.Join(projectDir, tc.entry), projectDir, results); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+
+			if len(results) != len(tc.expected) {
+				t.Fatalf("Expected %d files, got %d: %v", len(tc.expected), len(results), keys(results))
+			}
+			for _, path := range tc.expected {
+				fullPath := filepath.Join(projectDir, path)
+				if _, ok := results[fullPath]; !ok {
+					t.Errorf("Expected file %s not found in results", path)
+				}
+			}
+
+			// Everything but the entry file comes from a package and is marked as external
+			if len(externalFiles) != len(tc.expected)-1 {
+				t.Errorf("Expected %d external files, got %v", len(tc.expected)-1, externalFiles)
+			}
+			if output := FormatResults(results); len(tc.expected) > 1 && !strings.Contains(output, "(external: ") {
+				t.Errorf("Expected output to mark external files")
+			}
+		})
+	}
+}
+
+// TestExternalPackageOf tests detecting which package a third-party file belongs to
+func TestExternalPackageOf(t *testing.T) {
+	t.Setenv("GOMODCACHE", "/cache/mod")
+
+	testCases := map[string]string{
+		"/app/node_modules/lodash/index.js":                     "lodash",
+		"/app/node_modules/@acme/ui/dist/index.js":              "@acme/ui",
+		"/app/.venv/lib/python3.12/site-packages/requests/a.py": "requests",
+		"/app/vendor/github.com/pkg/errors/errors.go":           "github.com/pkg/errors",
+		"/app/vendor/monolog/monolog/src/Logger.php":            "monolog/monolog",
+		"/cache/mod/github.com/!burnt!sushi/toml@v1.3.2/a.go":   "github.com/BurntSushi/toml",
+		"/app/src/index.js":                                     "",
+	}
+
+	for path, expected := range testCases {
+		if got := externalPackageOf(filepath.FromSlash(path), filepath.FromSlash("/app")); got != expected {
+			t.Errorf("externalPackageOf(%q) = %q, want %q", path, got, expected)
+		}
+	}
+}
+
+// keys returns the keys of a results map for error messages
+func keys(results map[string]string) []string {
+	var paths []string
+	for path := range results {
+		paths = append(paths, path)
+	}
+	return paths
+}
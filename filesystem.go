@@ -0,0 +1,249 @@
+package main
+
+import (
+	"bytes"
+	"io"
+	"io/fs"
+	"os"
+	"path"
+	"path/filepath"
+	"strings"
+	"time"
+)
+
+// Source of files for the current run. Absolute paths are looked up in it by their fs name, the
+// path without its volume and leading separator, so the default reads the whole disk. Other
+// sources, like a git revision, are mounted over part of it with mountFS.
+var fileSystem fs.FS = osFS{os.DirFS("/")}
+
+// realPathFS is implemented by file systems with symbolic links
+type realPathFS interface {
+	// RealPath resolves symbolic links in the named file and returns its fs name
+	RealPath(name string) (string, error)
+}
+
+// osFS is the disk, rooted at / so any absolute path can be read
+type osFS struct {
+	fs.FS
+}
+
+// RealPath resolves symbolic links on disk
+func (osFS) RealPath(name string) (string, error) {
+	resolved, err := filepath.EvalSymlinks(string(filepath.Separator) + filepath.FromSlash(name))
+	if err != nil {
+		return "", err
+	}
+	return fsName(resolved), nil
+}
+
+// fsName converts an absolute path to its name in fileSystem
+func fsName(absPath string) string {
+	name := filepath.ToSlash(absPath[len(filepath.VolumeName(absPath)):])
+	name = strings.TrimLeft(path.Clean(name), "/")
+	if name == "" {
+		return "."
+	}
+	return name
+}
+
+// osPath converts a name in fileSystem back to an absolute path on the volume of like
+func osPath(name string, like string) string {
+	if name == "." {
+		name = ""
+	}
+	return filepath.VolumeName(like) + string(filepath.Separator) + filepath.FromSlash(name)
+}
+
+// statFile returns information about a file in the current file system
+func statFile(path string) (fs.FileInfo, error) {
+	return fs.Stat(fileSystem, fsName(path))
+}
+
+// readFile reads a file from the current file system
+func readFile(path string) ([]byte, error) {
+	return fs.ReadFile(fileSystem, fsName(path))
+}
+
+// readDir lists a directory in the current file system, sorted by name
+func readDir(path string) ([]fs.DirEntry, error) {
+	return fs.ReadDir(fileSystem, fsName(path))
+}
+
+// realPath resolves symbolic links in a path in the current file system. File systems without
+// links return the path unchanged if it exists.
+func realPath(path string) (string, error) {
+	if linkFS, ok := fileSystem.(realPathFS); ok {
+		resolved, err := linkFS.RealPath(fsName(path))
+		if err != nil {
+			return "", err
+		}
+		return osPath(resolved, path), nil
+	}
+	if _, err := statFile(path); err != nil {
+		return "", err
+	}
+	return filepath.Clean(path), nil
+}
+
+// globFiles returns the paths matching a pattern in the current file system, like filepath.Glob
+func globFiles(pattern string) ([]string, error) {
+	names, err := fs.Glob(fileSystem, fsName(pattern))
+	if err != nil {
+		return nil, err
+	}
+	var matches []string
+	for _, name := range names {
+		matches = append(matches, osPath(name, pattern))
+	}
+	return matches, nil
+}
+
+// walkDir walks a directory tree in the current file system, like filepath.WalkDir
+func walkDir(root string, fn fs.WalkDirFunc) error {
+	return fs.WalkDir(fileSystem, fsName(root), func(name string, entry fs.DirEntry, err error) error {
+		return fn(osPath(name, root), entry, err)
+	})
+}
+
+// mountFS serves the names under dir from fsys, and everything else from parent
+type mountFS struct {
+	dir    string
+	fsys   fs.FS
+	parent fs.FS
+}
+
+// newMountFS mounts fsys at the absolute path dir of parent
+func newMountFS(dir string, fsys fs.FS, parent fs.FS) *mountFS {
+	return &mountFS{dir: fsName(dir), fsys: fsys, parent: parent}
+}
+
+// resolve returns the file system holding a name and the name within it
+func (m *mountFS) resolve(name string) (fs.FS, string, bool) {
+	switch {
+	case name == m.dir:
+		return m.fsys, ".", true
+	case m.dir == ".":
+		return m.fsys, name, true
+	case strings.HasPrefix(name, m.dir+"/"):
+		return m.fsys, name[len(m.dir)+1:], true
+	}
+	return m.parent, name, false
+}
+
+// Open opens the named file in whichever file system holds it
+func (m *mountFS) Open(name string) (fs.File, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: fs.ErrInvalid}
+	}
+	fsys, inner, _ := m.resolve(name)
+	file, err := fsys.Open(inner)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: unwrapPathError(err)}
+	}
+	if dir, ok := file.(fs.ReadDirFile); ok && inner == "." && name != "." {
+		// The root of the mounted file system is named after the mount point
+		return &mountRoot{ReadDirFile: dir, name: path.Base(name)}, nil
+	}
+	return file, nil
+}
+
+// mountRoot is the root directory of a mounted file system
+type mountRoot struct {
+	fs.ReadDirFile
+	name string
+}
+
+// Stat describes the directory by the name of the mount point
+func (r *mountRoot) Stat() (fs.FileInfo, error) {
+	info, err := r.ReadDirFile.Stat()
+	if err != nil {
+		return nil, err
+	}
+	return &memFileInfo{name: r.name, size: info.Size(), mode: info.Mode(), modTime: info.ModTime()}, nil
+}
+
+// RealPath resolves symbolic links in whichever file system holds the name
+func (m *mountFS) RealPath(name string) (string, error) {
+	fsys, inner, mounted := m.resolve(name)
+	resolved := inner
+	if linkFS, ok := fsys.(realPathFS); ok {
+		var err error
+		if resolved, err = linkFS.RealPath(inner); err != nil {
+			return "", err
+		}
+	} else if _, err := fs.Stat(fsys, inner); err != nil {
+		return "", err
+	}
+
+	if !mounted || m.dir == "." {
+		return resolved, nil
+	}
+	if resolved == "." {
+		return m.dir, nil
+	}
+	return m.dir + "/" + resolved, nil
+}
+
+// unwrapPathError returns the underlying error of a *fs.PathError, so it can be reported with the outer name
+func unwrapPathError(err error) error {
+	if pathErr, ok := err.(*fs.PathError); ok {
+		return pathErr.Err
+	}
+	return err
+}
+
+// memFile is an open file or directory of an in-memory file system
+type memFile struct {
+	info    fs.FileInfo
+	reader  *bytes.Reader
+	entries []fs.DirEntry
+}
+
+// newMemFile opens a file with the given contents, or a directory with the given entries
+func newMemFile(info fs.FileInfo, content []byte, entries []fs.DirEntry) *memFile {
+	return &memFile{info: info, reader: bytes.NewReader(content), entries: entries}
+}
+
+func (f *memFile) Stat() (fs.FileInfo, error) { return f.info, nil }
+func (f *memFile) Close() error               { return nil }
+
+// Read reads from the contents of a file
+func (f *memFile) Read(p []byte) (int, error) {
+	if f.info.IsDir() {
+		return 0, &fs.PathError{Op: "read", Path: f.info.Name(), Err: fs.ErrInvalid}
+	}
+	return f.reader.Read(p)
+}
+
+// ReadDir returns the next n entries of a directory, or all remaining entries if n <= 0
+func (f *memFile) ReadDir(n int) ([]fs.DirEntry, error) {
+	if !f.info.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: f.info.Name(), Err: fs.ErrInvalid}
+	}
+	if n <= 0 || n >= len(f.entries) {
+		entries := f.entries
+		f.entries = nil
+		if n > 0 && len(entries) == 0 {
+			return nil, io.EOF
+		}
+		return entries, nil
+	}
+	entries := f.entries[:n]
+	f.entries = f.entries[n:]
+	return entries, nil
+}
+
+// memFileInfo describes a file of an in-memory file system
+type memFileInfo struct {
+	name    string
+	size    int64
+	mode    fs.FileMode
+	modTime time.Time
+}
+
+func (i *memFileInfo) Name() string       { return i.name }
+func (i *memFileInfo) Size() int64        { return i.size }
+func (i *memFileInfo) Mode() fs.FileMode  { return i.mode }
+func (i *memFileInfo) ModTime() time.Time { return i.modTime }
+func (i *memFileInfo) IsDir() bool        { return i.mode.IsDir() }
+func (i *memFileInfo) Sys() any           { return nil }
@@ -0,0 +1,174 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestProcessFileInMemory tests collecting files from an in-memory file system
+func TestProcessFileInMemory(t *testing.T) {
+	defer func(original fs.FS) { fileSystem = original }(fileSystem)
+	fileSystem = fstest.MapFS{
+		"project/go.mod":                      {Data: []byte("module example.com/project\n")},
+		"project/package.json":                {Data: []byte(`{"name": "web"}`)},
+		"project/web/app.ts":                  {Data: []byte("import { api } from './api';\nimport './styles/main.scss';\n")},
+		"project/web/api/index.ts":            {Data: []byte("export * from '../util';\n")},
+		"project/web/util.js":                 {Data: []byte("export const util = 1;\n")},
+		"project/web/util.d.ts":               {Data: []byte("export declare const util: number;\n")},
+		"project/web/styles/main.scss":        {Data: []byte("@use 'colors';\n")},
+		"project/web/styles/_colors.scss":     {Data: []byte("$red: #f00;\n")},
+		"project/cmd/server/main.go":          {Data: []byte("package main\n\nimport \"example.com/project/internal/db\"\n")},
+		"project/internal/db/db.go":           {Data: []byte("package db\n")},
+		"project/internal/db/db_test.go":      {Data: []byte("package db\n")},
+		"project/scripts/tool.py":             {Data: []byte("from helpers import run\n")},
+		"project/scripts/helpers/__init__.py": {Data: []byte("")},
+		"project/scripts/helpers/run.py":      {Data: []byte("")},
+	}
+	root := filepath.Join(string(filepath.Separator), "project")
+
+	found, err := FindProjectRoot(filepath.Join(root, "web", "app.ts"))
+	if err != nil || found != root {
+		t.Fatalf("Expected project root %s, got %s (%v)", root, found, err)
+	}
+
+	tests := []struct {
+		entry    string
+		expected []string
+	}{
+		{"web/app.ts", []string{"web/app.ts", "web/api/index.ts", "web/util.js", "web/util.d.ts", "web/styles/main.scss", "web/styles/_colors.scss"}},
+		{"cmd/server/main.go", []string{"cmd/server/main.go", "internal/db/db.go"}},
+		{"scripts/tool.py", []string{"scripts/tool.py", "scripts/helpers/__init__.py", "scripts/helpers/run.py"}},
+	}
+
+	for _, test := range tests {
+		t.Run(test.entry, func(t *testing.T) {
+			processedFiles = make(map[string]struct{})
+			tsTypeRoots = make(map[string][]string)
+			results := make(map[string]string)
+			if err := ProcessFile(filepath.Join(root, test.entry), root, results); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+			if len(results) != len(test.expected) {
+				t.Errorf("Expected %d files, got %d: %v", len(test.expected), len(results), resultPaths(results))
+			}
+			for _, path := range test.expected {
+				if _, ok := results[filepath.Join(root, path)]; !ok {
+					t.Errorf("Expected %s to be included, got %v", path, resultPaths(results))
+				}
+			}
+		})
+	}
+}
+
+// TestMountFS tests serving part of a file system from another
+func TestMountFS(t *testing.T) {
+	disk := fstest.MapFS{
+		"home/user/notes.txt":       {Data: []byte("notes")},
+		"home/user/repro/stale.txt": {Data: []byte("hidden by the mount")},
+		"home/user/repro.txt":       {Data: []byte("not under the mount")},
+	}
+	archive := fstest.MapFS{
+		"src/app.ts": {Data: []byte("archived")},
+	}
+	mounted := newMountFS(filepath.Join(string(filepath.Separator), "home", "user", "repro"), archive, disk)
+
+	if err := fstest.TestFS(mounted, "home/user/notes.txt", "home/user/repro/src/app.ts", "home/user/repro.txt"); err != nil {
+		t.Errorf("mountFS doesn't behave as an fs.FS: %v", err)
+	}
+
+	tests := []struct {
+		name    string
+		content string
+		exists  bool
+	}{
+		{"home/user/repro/src/app.ts", "archived", true},
+		{"home/user/repro/stale.txt", "", false},
+		{"home/user/repro.txt", "not under the mount", true},
+		{"home/user/notes.txt", "notes", true},
+	}
+	for _, test := range tests {
+		content, err := fs.ReadFile(mounted, test.name)
+		if (err == nil) != test.exists {
+			t.Errorf("ReadFile(%s): expected exists=%v, got %v", test.name, test.exists, err)
+			continue
+		}
+		if string(content) != test.content {
+			t.Errorf("ReadFile(%s): expected %q, got %q", test.name, test.content, content)
+		}
+	}
+}
+
+// TestFSName tests converting between absolute paths and file system names
+func TestFSName(t *testing.T) {
+	sep := string(filepath.Separator)
+	tests := []struct {
+		path string
+		name string
+	}{
+		{sep, "."},
+		{filepath.Join(sep, "project", "src", "app.ts"), "project/src/app.ts"},
+		{filepath.Join(sep, "project", "src") + sep, "project/src"},
+	}
+
+	for _, test := range tests {
+		if name := fsName(test.path); name != test.name {
+			t.Errorf("fsName(%q): expected %q, got %q", test.path, test.name, name)
+		}
+		if path := osPath(test.name, test.path); path != filepath.Clean(test.path) {
+			t.Errorf("osPath(%q): expected %q, got %q", test.name, filepath.Clean(test.path), path)
+		}
+	}
+}
+
+// resultPaths lists the collected paths, for test failure messages
+func resultPaths(results map[string]string) []string {
+	var paths []string
+	for path := range results {
+		paths = append(paths, path)
+	}
+	return paths
+}
+
+// TestWalkDirInMemory tests walking and globbing an in-memory file system by absolute path
+func TestWalkDirInMemory(t *testing.T) {
+	defer func(original fs.FS) { fileSystem = original }(fileSystem)
+	fileSystem = fstest.MapFS{
+		"app/a.rb":              {Data: []byte("")},
+		"app/models/b.rb":       {Data: []byte("")},
+		"app/node_modules/c.rb": {Data: []byte("")},
+	}
+	root := filepath.Join(string(filepath.Separator), "app")
+
+	var walked []string
+	walkDir(root, func(path string, d fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if d.IsDir() && d.Name() == "node_modules" {
+			return filepath.SkipDir
+		}
+		if !d.IsDir() {
+			walked = append(walked, path)
+		}
+		return nil
+	})
+	expected := []string{filepath.Join(root, "a.rb"), filepath.Join(root, "models", "b.rb")}
+	if strings.Join(walked, ",") != strings.Join(expected, ",") {
+		t.Errorf("Expected walk to visit %v, got %v", expected, walked)
+	}
+
+	matches, err := globFiles(filepath.Join(root, "*", "*.rb"))
+	if err != nil || len(matches) != 2 {
+		t.Errorf("Expected 2 glob matches, got %v (%v)", matches, err)
+	}
+
+	if resolved, err := realPath(filepath.Join(root, "a.rb")); err != nil || resolved != filepath.Join(root, "a.rb") {
+		t.Errorf("Expected a.rb to resolve to itself, got %s (%v)", resolved, err)
+	}
+	if _, err := realPath(filepath.Join(root, "missing.rb")); err == nil {
+		t.Errorf("Expected an error resolving a missing file")
+	}
+}
@@ -1,7 +1,12 @@
 package main
 
 import (
+	"fmt"
+	"os"
+	"path/filepath"
 	"regexp"
+	"sort"
+	"strings"
 )
 
 // Store files that have already been processed to avoid duplicates
@@ -9,93 +14,123 @@ var processedFiles = make(map[string]struct{})
 
 // File extensions to consider for import analysis
 var supportedExtensions = map[string]struct{}{
-	".go":    {},
-	".js":    {},
-	".jsx":   {},
-	".ts":    {},
-	".tsx":   {},
-	".py":    {},
-	".html":  {},
-	".css":   {},
-	".json":  {},
-	".vue":   {},
-	".scss":  {},
-	".sass":  {},
-	".less":  {},
-	".mjs":   {},
-	".cjs":   {},
-	".rs":    {},
-	".rb":    {},
-	".php":   {},
-	".java":  {},
-	".swift": {},
-	".kt":    {},
+	".go":      {},
+	".js":      {},
+	".jsx":     {},
+	".ts":      {},
+	".tsx":     {},
+	".mts":     {},
+	".cts":     {},
+	".d.ts":    {},
+	".d.mts":   {},
+	".d.cts":   {},
+	".py":      {},
+	".html":    {},
+	".htm":     {},
+	".css":     {},
+	".json":    {},
+	".vue":     {},
+	".svelte":  {},
+	".astro":   {},
+	".scss":    {},
+	".sass":    {},
+	".less":    {},
+	".mjs":     {},
+	".cjs":     {},
+	".rs":      {},
+	".rb":      {},
+	".php":     {},
+	".java":    {},
+	".swift":   {},
+	".kt":      {},
+	".c":       {},
+	".h":       {},
+	".cc":      {},
+	".cpp":     {},
+	".cxx":     {},
+	".hh":      {},
+	".hpp":     {},
+	".hxx":     {},
+	".graphql": {},
+	".gql":     {},
+	".proto":   {},
+	".cs":      {},
+	".csproj":  {},
+	".sln":     {},
 }
 
-// ImportPatterns maps file extensions to regular expressions that match import statements
+// isSupportedExtension reports whether files with an extension are collected: ones listed in
+// supportedExtensions, and ones claimed by a language added with RegisterLanguage. Images and
+// other assets are only collected as descriptions, with -include-assets.
+func isSupportedExtension(fileExt string) bool {
+	if _, ok := supportedExtensions[fileExt]; ok {
+		return true
+	}
+	if _, ok := assetExtensions[strings.ToLower(fileExt)]; ok {
+		return false
+	}
+	return languageFor(fileExt) != nil
+}
+
+// isSourceExtension reports whether files with an extension are collected source that can import
+// other files, as opposed to data files like .json
+func isSourceExtension(fileExt string) bool {
+	if !isSupportedExtension(fileExt) {
+		return false
+	}
+	lang := languageFor(fileExt)
+	if _, ok := lang.(patternLanguage); ok {
+		return len(importPatterns[fileExt]) > 0
+	}
+	return lang != nil
+}
+
+// Extensions of TypeScript declaration files, which filepath.Ext would report as .ts
+var declarationExtensions = []string{".d.ts", ".d.mts", ".d.cts"}
+
+// Order in which extensions are tried for extensionless imports. Sources come before declarations,
+// which are included as companions of JavaScript modules instead.
+var resolutionOrder = []string{".ts", ".tsx", ".js", ".jsx", ".mts", ".cts", ".mjs", ".cjs", ".d.ts", ".d.mts", ".d.cts"}
+
+// fileExtension returns the extension of a path, treating compound extensions like .d.ts as one
+func fileExtension(path string) string {
+	base := strings.ToLower(filepath.Base(path))
+	for _, ext := range declarationExtensions {
+		if strings.HasSuffix(base, ext) && len(base) > len(ext) {
+			return ext
+		}
+	}
+	return filepath.Ext(path)
+}
+
+// candidateExtensions returns the supported extensions in the order they are tried when resolving
+// an extensionless import, so resolution doesn't depend on map iteration order
+func candidateExtensions() []string {
+	candidates := append([]string{}, resolutionOrder...)
+
+	var rest []string
+	for ext := range supportedExtensions {
+		if !containsString(resolutionOrder, ext) {
+			rest = append(rest, ext)
+		}
+	}
+	sort.Strings(rest)
+
+	return append(candidates, rest...)
+}
+
+// ImportPatterns maps file extensions to regular expressions that match import statements, for
+// languages without their own Language implementation. Capture group 1 is the imported path.
 var importPatterns = map[string][]*regexp.Regexp{
-	".js": {
-		regexp.MustCompile(`import\s+.*?\s+from\s+['"](.+?)['"]`),
-		regexp.MustCompile(`import\s+['"](.+?)['"]`),
-		regexp.MustCompile(`require\(['"](.+?)['"]\)`),
-		regexp.MustCompile(`import\(.*?['"](.+?)['"].*?\)`),
-	},
-	".jsx": {
-		regexp.MustCompile(`import\s+.*?\s+from\s+['"](.+?)['"]`),
-		regexp.MustCompile(`import\s+['"](.+?)['"]`),
-		regexp.MustCompile(`require\(['"](.+?)['"]\)`),
-	},
-	".ts": {
-		regexp.MustCompile(`import\s+.*?\s+from\s+['"](.+?)['"]`),
-		regexp.MustCompile(`import\s+['"](.+?)['"]`),
-		regexp.MustCompile(`import\s+type\s+.*?\s+from\s+['"](.+?)['"]`),
-		regexp.MustCompile(`require\(['"](.+?)['"]\)`),
-	},
-	".tsx": {
-		regexp.MustCompile(`import\s+.*?\s+from\s+['"](.+?)['"]`),
-		regexp.MustCompile(`import\s+['"](.+?)['"]`),
-		regexp.MustCompile(`import\s+type\s+.*?\s+from\s+['"](.+?)['"]`),
-		regexp.MustCompile(`require\(['"](.+?)['"]\)`),
-	},
-	".py": {
-		regexp.MustCompile(`from\s+(\S+)\s+import\s+`),
-		regexp.MustCompile(`import\s+(\S+)`),
-		regexp.MustCompile(`import\s+(\S+)\s+as\s+`),
-	},
-	".go": {
-		regexp.MustCompile(`import\s+[(\s]+"(.+?)"`),
-		regexp.MustCompile(`import\s+(\S+)\s+".+?"`),
-	},
-	".html": {
-		regexp.MustCompile(`<script\s+src=['"](.+?)['"]\s*>`),
-		regexp.MustCompile(`<link\s+.*?href=['"](.+?)['"]\s*>`),
-		regexp.MustCompile(`<img\s+.*?src=['"](.+?)['"]\s*>`),
-	},
 	".css": {
 		regexp.MustCompile(`@import\s+['"](.+?)['"]`),
 		regexp.MustCompile(`@import\s+url\(['"](.+?)['"]\)`),
 	},
-	".scss": {
-		regexp.MustCompile(`@import\s+['"](.+?)['"]`),
-		regexp.MustCompile(`@import\s+url\(['"](.+?)['"]\)`),
-		regexp.MustCompile(`@use\s+['"](.+?)['"]`),
-	},
-	".vue": {
-		regexp.MustCompile(`import\s+.*?\s+from\s+['"](.+?)['"]`),
-		regexp.MustCompile(`import\s+['"](.+?)['"]`),
-		regexp.MustCompile(`require\(['"](.+?)['"]\)`),
-		regexp.MustCompile(`<script\s+src=['"](.+?)['"]\s*>`),
-	},
 	".php": {
 		regexp.MustCompile(`require[_once]*\s*\(['"](.+?)['"]\)`),
 		regexp.MustCompile(`include[_once]*\s*\(['"](.+?)['"]\)`),
 		regexp.MustCompile(`use\s+([^;]+)`),
 	},
-	".rb": {
-		regexp.MustCompile(`require\s+['"](.+?)['"]`),
-		regexp.MustCompile(`require_relative\s+['"](.+?)['"]`),
-		regexp.MustCompile(`load\s+['"](.+?)['"]`),
-	},
 	".java": {
 		regexp.MustCompile(`import\s+([^;]+)`),
 	},
@@ -104,23 +139,67 @@ var importPatterns = map[string][]*regexp.Regexp{
 	},
 }
 
-// Initialize patterns for other file types that use the same patterns as JS
+// Initialize patterns for file types that are included but have no imports
 func init() {
-	// JavaScript-like imports
-	jsLike := []string{".mjs", ".cjs"}
-	for _, ext := range jsLike {
-		importPatterns[ext] = importPatterns[".js"]
-	}
-
-	// CSS-like imports
-	cssLike := []string{".less", ".sass"}
-	for _, ext := range cssLike {
-		importPatterns[ext] = importPatterns[".css"]
-	}
-
 	// File types that typically don't have imports but may be referenced
 	noImports := []string{".json", ".svg", ".png", ".jpg", ".jpeg", ".gif"}
 	for _, ext := range noImports {
 		importPatterns[ext] = []*regexp.Regexp{}
 	}
+
+	RegisterLanguage(patternLanguage{})
+}
+
+// patternLanguage extracts imports with the regular expressions in importPatterns and resolves
+// them with ResolveImportPath
+type patternLanguage struct{}
+
+// Name identifies the language
+func (patternLanguage) Name() string {
+	return "patterns"
+}
+
+// Detect reports whether there are import patterns for an extension
+func (patternLanguage) Detect(fileExt string) bool {
+	_, ok := importPatterns[fileExt]
+	return ok
+}
+
+// ExtractSpecifiers returns the first capture group of every pattern match
+func (patternLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+	for _, pattern := range importPatterns[file.Ext] {
+		for _, match := range pattern.FindAllSubmatch(file.Content, -1) {
+			if len(match) >= 2 {
+				specs = append(specs, Specifier{Path: string(match[1])})
+			}
+		}
+	}
+	return specs
+}
+
+// IsBuiltin is always false, as patterns carry no knowledge of standard libraries
+func (patternLanguage) IsBuiltin(spec Specifier) bool {
+	return false
+}
+
+// ResolveSpecifier resolves paths and aliases that look like they're part of the project
+func (patternLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	resolvedPath, err := ResolveImportPath(spec.Path, filepath.Dir(file.Path), file.ProjectRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not resolve import path %s: %v\n", spec.Path, err)
+		return nil
+	}
+
+	// Check if this is a relative path that might be part of the project
+	if strings.HasPrefix(spec.Path, ".") ||
+		strings.HasPrefix(spec.Path, "/") ||
+		strings.HasPrefix(spec.Path, "@") ||
+		strings.HasPrefix(spec.Path, "~") ||
+		// For local imports without special prefixes
+		(!strings.Contains(spec.Path, "/") && !isBuiltinModule(spec.Path, file.Ext)) {
+		return []string{resolvedPath}
+	}
+	// Skip external imports (node_modules, npm packages, etc.)
+	return nil
 }
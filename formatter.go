@@ -2,35 +2,152 @@ package main
 
 import (
 	"fmt"
+	"os"
+	"os/exec"
+	"runtime"
+	"sort"
 	"strings"
+	"time"
 )
 
 // FormatResults formats the collected file contents for output
 func FormatResults(results map[string]string) string {
 	var builder strings.Builder
 
-	for filePath, content := range results {
-		fmt.Fprintf(&builder, "{{ BEGIN CONTENTS OF %s }}\n", filePath)
-		fmt.Fprint(&builder, content)
-		if !strings.HasSuffix(content, "\n") {
-			fmt.Fprint(&builder, "\n")
-		}
-		fmt.Fprintf(&builder, "{{ END CONTENTS OF %s }}\n\n", filePath)
+	// Files are sorted by path so the same files always give the same output
+	paths := make([]string, 0, len(results))
+	for filePath := range results {
+		paths = append(paths, filePath)
 	}
+	sort.Strings(paths)
 
+	// Code comes first, then tooling config in its own section
+	// Only code counts towards the lines of code; config files are counted separately
+	var configPaths []string
 	totalLines := 0
-	for _, content := range results {
-		totalLines += strings.Count(content, "\n") + 1
+	for _, filePath := range paths {
+		if _, ok := configFiles[filePath]; ok {
+			configPaths = append(configPaths, filePath)
+			continue
+		}
+		writeFileContents(&builder, filePath, results[filePath])
+		totalLines += strings.Count(results[filePath], "\n") + 1
+	}
+	if len(configPaths) > 0 {
+		fmt.Fprintf(&builder, "------------------------------\n")
+		fmt.Fprintf(&builder, "Configuration\n")
+		fmt.Fprintf(&builder, "------------------------------\n\n")
+		for _, filePath := range configPaths {
+			writeFileContents(&builder, filePath, results[filePath])
+		}
 	}
 
 	fmt.Fprintf(&builder, "------------------------------\n")
-	fmt.Fprintf(&builder, "Generated %d lines of code from %d files\n", totalLines, len(results))
+	fmt.Fprintf(&builder, "Generated %d lines of code from %d files", totalLines, len(results)-len(configPaths))
+	if externalCount := countExternal(results); externalCount > 0 {
+		fmt.Fprintf(&builder, " (%d external)", externalCount)
+	}
+	if len(configPaths) > 0 {
+		fmt.Fprintf(&builder, " and %d config files", len(configPaths))
+	}
+	fmt.Fprint(&builder, "\n")
 
 	return builder.String()
 }
 
+// writeFileContents writes a file's contents between BEGIN and END markers
+func writeFileContents(builder *strings.Builder, filePath string, content string) {
+	if pkg, ok := externalFiles[filePath]; ok {
+		fmt.Fprintf(builder, "{{ BEGIN CONTENTS OF %s (external: %s) }}\n", filePath, pkg)
+	} else {
+		fmt.Fprintf(builder, "{{ BEGIN CONTENTS OF %s }}\n", filePath)
+	}
+	fmt.Fprint(builder, content)
+	if !strings.HasSuffix(content, "\n") {
+		fmt.Fprint(builder, "\n")
+	}
+	fmt.Fprintf(builder, "{{ END CONTENTS OF %s }}\n\n", filePath)
+
+	if blame, ok := fileBlame[filePath]; ok {
+		fmt.Fprintf(builder, "{{ BEGIN BLAME OF %s:%d-%d }}\n", filePath, blame.Start, blame.End)
+		for _, line := range blame.Lines {
+			fmt.Fprintln(builder, line)
+		}
+		fmt.Fprintf(builder, "{{ END BLAME OF %s:%d-%d }}\n\n", filePath, blame.Start, blame.End)
+	}
+
+	if commits, ok := fileHistory[filePath]; ok {
+		fmt.Fprintf(builder, "{{ BEGIN HISTORY OF %s }}\n", filePath)
+		for _, commit := range commits {
+			fmt.Fprintln(builder, commit)
+		}
+		fmt.Fprintf(builder, "{{ END HISTORY OF %s }}\n\n", filePath)
+	}
+
+	if diff, ok := fileDiffs[filePath]; ok {
+		fmt.Fprintf(builder, "{{ BEGIN DIFF OF %s }}\n", filePath)
+		fmt.Fprint(builder, diff)
+		if !strings.HasSuffix(diff, "\n") {
+			fmt.Fprint(builder, "\n")
+		}
+		fmt.Fprintf(builder, "{{ END DIFF OF %s }}\n\n", filePath)
+	}
+}
+
+// countExternal counts the results that come from third-party packages
+func countExternal(results map[string]string) int {
+	count := 0
+	for filePath := range results {
+		if _, ok := externalFiles[filePath]; ok {
+			count++
+		}
+	}
+	return count
+}
+
 // PrintResults outputs the formatted results to stdout and file
 func PrintResults(results map[string]string) {
 	formattedContent := FormatResults(results)
 	fmt.Print(formattedContent)
 }
+
+// WriteResultsToFile saves the formatted results to a timestamped file in the current directory
+func WriteResultsToFile(content string) (string, error) {
+	outputFile := fmt.Sprintf("error-context-%s.txt", time.Now().Format("20060102-150405"))
+	if err := os.WriteFile(outputFile, []byte(content), 0644); err != nil {
+		return "", err
+	}
+	return outputFile, nil
+}
+
+// CopyToClipboard copies content to the system clipboard with the platform's clipboard command
+func CopyToClipboard(content string) error {
+	var candidates [][]string
+	switch runtime.GOOS {
+	case "darwin":
+		candidates = [][]string{{"pbcopy"}}
+	case "windows":
+		candidates = [][]string{{"clip"}}
+	default:
+		candidates = [][]string{{"wl-copy"}, {"xclip", "-selection", "clipboard"}, {"xsel", "--clipboard", "--input"}}
+		// wl-copy only works in a Wayland session
+		if os.Getenv("WAYLAND_DISPLAY") == "" {
+			candidates = candidates[1:]
+		}
+	}
+
+	for _, candidate := range candidates {
+		if _, err := exec.LookPath(candidate[0]); err != nil {
+			continue
+		}
+		cmd := exec.Command(candidate[0], candidate[1:]...)
+		cmd.Stdin = strings.NewReader(content)
+		// xclip stays running to serve the selection, so its output isn't waited on through a pipe
+		cmd.Stderr = os.Stderr
+		if err := cmd.Run(); err != nil {
+			return fmt.Errorf("%s: %v", candidate[0], err)
+		}
+		return nil
+	}
+	return fmt.Errorf("no clipboard command found")
+}
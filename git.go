@@ -0,0 +1,468 @@
+package main
+
+import (
+	"bufio"
+	"bytes"
+	"fmt"
+	"io"
+	"io/fs"
+	"os"
+	"os/exec"
+	pathpkg "path"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"sync"
+)
+
+// Unified diffs of changed files, shown after their contents
+var fileDiffs = make(map[string]string)
+
+// optionalValueFlag is a flag that may be given alone or with a value, like -changed or -changed=main
+type optionalValueFlag struct {
+	set   bool
+	value string
+}
+
+// String returns the flag's value
+func (f *optionalValueFlag) String() string {
+	return f.value
+}
+
+// Set records that the flag was given, and its value if it had one
+func (f *optionalValueFlag) Set(value string) error {
+	switch value {
+	case "true":
+		f.set = true
+	case "false":
+		f.set = false
+	default:
+		f.set = true
+		f.value = value
+	}
+	return nil
+}
+
+// IsBoolFlag lets the flag be given without a value
+func (f *optionalValueFlag) IsBoolFlag() bool {
+	return true
+}
+
+// ProcessChanged uses the files changed relative to ref, plus untracked files, as entry points and
+// returns the ones processed. With withDiff, each one's unified diff against ref is recorded for the output.
+func ProcessChanged(repoRoot string, ref string, results map[string]string, withDiff bool) ([]string, error) {
+	changed, err := changedSourceFiles(repoRoot, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var entries []string
+	for _, path := range changed {
+		if err := ProcessFile(path, repoRoot, results); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not process changed file %s: %v\n", path, err)
+			continue
+		}
+		entries = append(entries, path)
+		if withDiff {
+			if diff := fileDiff(repoRoot, ref, path); diff != "" {
+				fileDiffs[path] = diff
+			}
+		}
+	}
+
+	return entries, nil
+}
+
+// changedSourceFiles returns the changed files fixfiles can collect
+func changedSourceFiles(repoRoot string, ref string) ([]string, error) {
+	changed, err := changedFiles(repoRoot, ref)
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, path := range changed {
+		if isSupportedExtension(fileExtension(path)) {
+			files = append(files, path)
+		}
+	}
+	return files, nil
+}
+
+// changedFiles returns the files modified or staged relative to ref, and untracked files, as absolute paths.
+// Deleted files are left out as there's nothing to read.
+func changedFiles(repoRoot string, ref string) ([]string, error) {
+	modified, err := gitOutput(repoRoot, "diff", "--name-only", "--diff-filter=d", ref, "--")
+	if err != nil {
+		return nil, err
+	}
+	untracked, err := gitOutput(repoRoot, "ls-files", "--others", "--exclude-standard")
+	if err != nil {
+		return nil, err
+	}
+
+	var files []string
+	for _, line := range strings.Split(modified+untracked, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			path := filepath.Join(repoRoot, filepath.FromSlash(line))
+			if !containsString(files, path) {
+				files = append(files, path)
+			}
+		}
+	}
+	return files, nil
+}
+
+// fileDiff returns the unified diff of a file against ref, or against nothing for untracked files
+func fileDiff(repoRoot string, ref string, path string) string {
+	rel, err := filepath.Rel(repoRoot, path)
+	if err != nil {
+		return ""
+	}
+	rel = filepath.ToSlash(rel)
+
+	if diff, err := gitOutput(repoRoot, "diff", ref, "--", rel); err == nil && diff != "" {
+		return diff
+	}
+	// --no-index exits with 1 when the files differ, which they always do here
+	diff, _ := gitOutput(repoRoot, "diff", "--no-index", "--", os.DevNull, rel)
+	return diff
+}
+
+// gitRepoRoot returns the top-level directory of the repository containing dir
+func gitRepoRoot(dir string) (string, error) {
+	root, err := gitOutput(dir, "rev-parse", "--show-toplevel")
+	if err != nil {
+		return "", err
+	}
+	return filepath.Clean(strings.TrimSpace(root)), nil
+}
+
+// gitOutput runs git in dir and returns its output. Exit status 1 from diff --no-index is reported
+// along with the output.
+func gitOutput(dir string, args ...string) (string, error) {
+	cmd := exec.Command("git", args...)
+	cmd.Dir = dir
+	var stderr bytes.Buffer
+	cmd.Stderr = &stderr
+
+	out, err := cmd.Output()
+	if err != nil {
+		if message := strings.TrimSpace(stderr.String()); message != "" {
+			return string(out), fmt.Errorf("git %s: %s", args[0], message)
+		}
+		return string(out), fmt.Errorf("git %s: %v", args[0], err)
+	}
+	return string(out), nil
+}
+
+// gitRevFS is a repository as it was at a revision, read from the git object store. Names are
+// relative to the repository root. Files that aren't tracked, like node_modules, don't exist in it.
+type gitRevFS struct {
+	repoRoot string
+	commit   string
+	// Tracked files and directories by name
+	entries  map[string]*memFileInfo
+	children map[string][]string
+	// Object IDs of tracked files and links by name
+	objects map[string]string
+	// Blobs read so far, and the git cat-file --batch process reading them, guarded by mu as
+	// workers read concurrently
+	mu       sync.Mutex
+	contents map[string][]byte
+	batch    *catFileBatch
+}
+
+// catFileBatch is a running git cat-file --batch, which reads objects one request at a time
+type catFileBatch struct {
+	cmd    *exec.Cmd
+	stdin  io.WriteCloser
+	stdout *bufio.Reader
+}
+
+// newGitRevFS lists the tree of a revision of the repository at repoRoot
+func newGitRevFS(repoRoot string, rev string) (*gitRevFS, error) {
+	commit, err := gitOutput(repoRoot, "rev-parse", "--verify", "--quiet", rev+"^{commit}")
+	if err != nil {
+		return nil, fmt.Errorf("unknown revision %s", rev)
+	}
+
+	gfs := &gitRevFS{
+		repoRoot: repoRoot,
+		commit:   strings.TrimSpace(commit),
+		entries:  map[string]*memFileInfo{".": {name: ".", mode: fs.ModeDir | 0755}},
+		children: make(map[string][]string),
+		objects:  make(map[string]string),
+		contents: make(map[string][]byte),
+	}
+	// The repository itself still marks the project root, though it's not listed in directories
+	gfs.entries[".git"] = &memFileInfo{name: ".git", mode: fs.ModeDir | 0755}
+
+	tree, err := gitOutput(repoRoot, "ls-tree", "-r", "-t", "-l", "-z", "--full-tree", gfs.commit)
+	if err != nil {
+		return nil, err
+	}
+	// Each entry is "<mode> <type> <object> <size>\t<path>", with a size of - for trees
+	for _, record := range strings.Split(tree, "\x00") {
+		meta, name, found := strings.Cut(record, "\t")
+		fields := strings.Fields(meta)
+		if !found || len(fields) != 4 {
+			continue
+		}
+
+		entry := &memFileInfo{name: pathpkg.Base(name), mode: 0644}
+		switch fields[0] {
+		case "040000":
+			entry.mode = fs.ModeDir | 0755
+		case "100755":
+			entry.mode = 0755
+		case "120000":
+			entry.mode = fs.ModeSymlink | 0777
+		case "160000":
+			// Submodules aren't part of the tree's contents
+			continue
+		}
+		entry.size, _ = strconv.ParseInt(fields[3], 10, 64)
+		if fields[1] == "blob" {
+			gfs.objects[name] = fields[2]
+		}
+
+		gfs.entries[name] = entry
+		parent := pathpkg.Dir(name)
+		gfs.children[parent] = append(gfs.children[parent], name)
+	}
+	for _, names := range gfs.children {
+		sort.Strings(names)
+	}
+
+	return gfs, nil
+}
+
+// lookup finds the entry for a name in the tree, following symbolic links in any part of it, like
+// a/link/b.ts where a/link points to ../real
+func (g *gitRevFS) lookup(op string, name string) (string, *memFileInfo, error) {
+	if !fs.ValidPath(name) {
+		return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrInvalid}
+	}
+
+	resolved := "."
+	pending := strings.Split(name, "/")
+	for links := 0; len(pending) > 0; {
+		next := pathpkg.Join(resolved, pending[0])
+		pending = pending[1:]
+		entry, ok := g.entries[next]
+		if !ok {
+			return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		if entry.mode&fs.ModeSymlink == 0 {
+			resolved = next
+			continue
+		}
+
+		if links++; links > 40 {
+			return "", nil, &fs.PathError{Op: op, Path: name, Err: fmt.Errorf("too many links")}
+		}
+		target, err := g.blob(next)
+		if err != nil {
+			return "", nil, &fs.PathError{Op: op, Path: name, Err: err}
+		}
+		linked := pathpkg.Join(resolved, string(target))
+		if pathpkg.IsAbs(string(target)) || !fs.ValidPath(linked) {
+			// Links out of the repository can't be followed at a revision
+			return "", nil, &fs.PathError{Op: op, Path: name, Err: fs.ErrNotExist}
+		}
+		// The rest of the name is looked up under the link's target
+		resolved = "."
+		pending = append(strings.Split(linked, "/"), pending...)
+	}
+
+	entry := g.entries[resolved]
+	if resolved != name {
+		// A link is described by its own name
+		linked := *entry
+		linked.name = pathpkg.Base(name)
+		entry = &linked
+	}
+	return resolved, entry, nil
+}
+
+// blob reads the contents of a tree entry from the object store
+func (g *gitRevFS) blob(name string) ([]byte, error) {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	if content, ok := g.contents[name]; ok {
+		return content, nil
+	}
+	object, ok := g.objects[name]
+	if !ok {
+		return nil, fs.ErrNotExist
+	}
+
+	content, err := g.readObject(object)
+	if err != nil {
+		return nil, err
+	}
+	g.contents[name] = content
+	return content, nil
+}
+
+// readObject reads a blob through the cat-file process, starting it on first use. The process
+// is stopped after an error, as its output may be out of step with the requests.
+func (g *gitRevFS) readObject(object string) ([]byte, error) {
+	if g.batch == nil {
+		cmd := exec.Command("git", "cat-file", "--batch")
+		cmd.Dir = g.repoRoot
+		stdin, err := cmd.StdinPipe()
+		if err != nil {
+			return nil, fmt.Errorf("git cat-file: %v", err)
+		}
+		stdout, err := cmd.StdoutPipe()
+		if err != nil {
+			return nil, fmt.Errorf("git cat-file: %v", err)
+		}
+		if err := cmd.Start(); err != nil {
+			return nil, fmt.Errorf("git cat-file: %v", err)
+		}
+		g.batch = &catFileBatch{cmd: cmd, stdin: stdin, stdout: bufio.NewReader(stdout)}
+	}
+
+	content, err := g.batch.read(object)
+	if err != nil {
+		g.closeBatch()
+		return nil, fmt.Errorf("git cat-file: %v", err)
+	}
+	return content, nil
+}
+
+// read requests an object and reads its contents, answered as "<object> <type> <size>\n<contents>\n"
+func (b *catFileBatch) read(object string) ([]byte, error) {
+	if _, err := fmt.Fprintln(b.stdin, object); err != nil {
+		return nil, err
+	}
+	header, err := b.stdout.ReadString('\n')
+	if err != nil {
+		return nil, err
+	}
+	fields := strings.Fields(header)
+	if len(fields) != 3 {
+		return nil, fmt.Errorf("object %s: %s", object, strings.TrimSpace(header))
+	}
+	size, err := strconv.Atoi(fields[2])
+	if err != nil {
+		return nil, fmt.Errorf("object %s: bad size %q", object, fields[2])
+	}
+
+	content := make([]byte, size+1)
+	if _, err := io.ReadFull(b.stdout, content); err != nil {
+		return nil, err
+	}
+	return content[:size], nil
+}
+
+// closeBatch stops the cat-file process, if it's running
+func (g *gitRevFS) closeBatch() {
+	if g.batch == nil {
+		return
+	}
+	g.batch.stdin.Close()
+	g.batch.cmd.Wait()
+	g.batch = nil
+}
+
+// Close stops reading from the object store. Files already read can still be opened.
+func (g *gitRevFS) Close() error {
+	g.mu.Lock()
+	defer g.mu.Unlock()
+	g.closeBatch()
+	return nil
+}
+
+// Open opens a file or directory as it was at the revision
+func (g *gitRevFS) Open(name string) (fs.File, error) {
+	resolved, entry, err := g.lookup("open", name)
+	if err != nil {
+		return nil, err
+	}
+	if entry.IsDir() {
+		entries, _ := g.ReadDir(resolved)
+		return newMemFile(entry, nil, entries), nil
+	}
+	content, err := g.blob(resolved)
+	if err != nil {
+		return nil, &fs.PathError{Op: "open", Path: name, Err: err}
+	}
+	return newMemFile(entry, content, nil), nil
+}
+
+// Stat returns information about a file at the revision without reading it
+func (g *gitRevFS) Stat(name string) (fs.FileInfo, error) {
+	_, entry, err := g.lookup("stat", name)
+	if err != nil {
+		return nil, err
+	}
+	return entry, nil
+}
+
+// ReadDir lists a directory as it was at the revision
+func (g *gitRevFS) ReadDir(name string) ([]fs.DirEntry, error) {
+	resolved, entry, err := g.lookup("readdir", name)
+	if err != nil {
+		return nil, err
+	}
+	if !entry.IsDir() {
+		return nil, &fs.PathError{Op: "readdir", Path: name, Err: fmt.Errorf("not a directory")}
+	}
+
+	var entries []fs.DirEntry
+	for _, child := range g.children[resolved] {
+		entries = append(entries, fs.FileInfoToDirEntry(g.entries[child]))
+	}
+	return entries, nil
+}
+
+// Lstat returns information about a file at the revision without following a final symbolic link
+func (g *gitRevFS) Lstat(name string) (fs.FileInfo, error) {
+	if !fs.ValidPath(name) {
+		return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrInvalid}
+	}
+	if entry, ok := g.entries[name]; ok {
+		return entry, nil
+	}
+	return nil, &fs.PathError{Op: "lstat", Path: name, Err: fs.ErrNotExist}
+}
+
+// ReadLink returns the target of a symbolic link at the revision
+func (g *gitRevFS) ReadLink(name string) (string, error) {
+	entry, err := g.Lstat(name)
+	if err != nil {
+		return "", err
+	}
+	if entry.Mode()&fs.ModeSymlink == 0 {
+		return "", &fs.PathError{Op: "readlink", Path: name, Err: fs.ErrInvalid}
+	}
+	target, err := g.blob(name)
+	return string(target), err
+}
+
+// RealPath resolves symbolic links in the named file at the revision
+func (g *gitRevFS) RealPath(name string) (string, error) {
+	resolved, _, err := g.lookup("lstat", name)
+	return resolved, err
+}
+
+// gitRepoRootFor returns the top-level directory of the repository containing path, a file or
+// directory which may not exist in the working tree
+func gitRepoRootFor(path string) (string, error) {
+	dir := path
+	for {
+		if info, err := os.Stat(dir); err == nil && info.IsDir() {
+			return gitRepoRoot(dir)
+		}
+		parent := filepath.Dir(dir)
+		if parent == dir {
+			return "", fmt.Errorf("no directory of %s exists", path)
+		}
+		dir = parent
+	}
+}
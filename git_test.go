@@ -0,0 +1,290 @@
+package main
+
+import (
+	"io/fs"
+	"os"
+	"os/exec"
+	"path/filepath"
+	"strings"
+	"testing"
+	"testing/fstest"
+)
+
+// TestProcessChanged tests using modified, staged and untracked files as entry points
+func TestProcessChanged(t *testing.T) {
+	tempDir, git, write := newTestRepo(t)
+	defer func() { fileDiffs = make(map[string]string) }()
+
+	write("src/app.js", "import { util } from './util';\n")
+	write("src/util.js", "export const util = 1;\n")
+	write("src/other.js", "export const other = 1;\n")
+	write("src/gone.js", "export const gone = 1;\n")
+	write("README.md", "# Project\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "initial")
+
+	// Modified but unstaged, staged, untracked, deleted and unsupported changes
+	write("src/app.js", "import { util } from './util';\nutil();\n")
+	write("src/other.js", "export const other = 2;\n")
+	git("add", "src/other.js")
+	write("src/new.js", "import { other } from './other';\n")
+	write("README.md", "# Project\n\nChanged\n")
+	if err := os.Remove(filepath.Join(tempDir, "src/gone.js")); err != nil {
+		t.Fatalf("Failed to remove file: %v", err)
+	}
+
+	root, err := gitRepoRoot(filepath.Join(tempDir, "src"))
+	if err != nil {
+		t.Fatalf("gitRepoRoot failed: %v", err)
+	}
+	if root != tempDir {
+		t.Errorf("Expected repo root %s, got %s", tempDir, root)
+	}
+
+	processedFiles = make(map[string]struct{})
+	fileDiffs = make(map[string]string)
+	results := make(map[string]string)
+	entries, err := ProcessChanged(root, "HEAD", results, true)
+	if err != nil {
+		t.Fatalf("ProcessChanged failed: %v", err)
+	}
+
+	expectedEntries := []string{"src/app.js", "src/other.js", "src/new.js"}
+	if len(entries) != len(expectedEntries) {
+		t.Fatalf("Expected entries %v, got %v", expectedEntries, entries)
+	}
+	for _, path := range expectedEntries {
+		if !containsString(entries, filepath.Join(tempDir, path)) {
+			t.Errorf("Expected entry %s, got %v", path, entries)
+		}
+	}
+
+	// Imports of changed files are followed as usual
+	if _, ok := results[filepath.Join(tempDir, "src/util.js")]; !ok {
+		t.Errorf("Expected src/util.js to be included as an import of src/app.js")
+	}
+	if len(results) != 4 {
+		t.Errorf("Expected 4 files, got %d", len(results))
+	}
+
+	// Diffs are recorded for changed files only, including untracked ones
+	diffs := map[string]string{
+		"src/app.js":   "+util();",
+		"src/other.js": "+export const other = 2;",
+		"src/new.js":   "+import { other } from './other';",
+	}
+	for path, line := range diffs {
+		if diff := fileDiffs[filepath.Join(tempDir, path)]; !strings.Contains(diff, line) {
+			t.Errorf("Expected diff of %s to contain %q, got %q", path, line, diff)
+		}
+	}
+	if _, ok := fileDiffs[filepath.Join(tempDir, "src/util.js")]; ok {
+		t.Errorf("Expected no diff for unchanged src/util.js")
+	}
+
+	output := FormatResults(results)
+	if !strings.Contains(output, "{{ BEGIN DIFF OF "+filepath.Join(tempDir, "src/app.js")+" }}") {
+		t.Errorf("Expected the diff of src/app.js in output")
+	}
+
+	// A bad ref is reported
+	if _, err := ProcessChanged(root, "no-such-ref", make(map[string]string), false); err == nil {
+		t.Errorf("Expected an error for an unknown ref")
+	}
+}
+
+// TestGitRevFS tests collecting files as they were at an earlier revision
+func TestGitRevFS(t *testing.T) {
+	tempDir, git, write := newTestRepo(t)
+	defer func(original fs.FS) { fileSystem = original }(fileSystem)
+
+	write("package.json", `{"name": "app"}`)
+	write("src/app.ts", "import { util } from './util';\n")
+	write("src/util.ts", "export const util = 1;\n")
+	write("src/lib/old.ts", "export const old = 1;\n")
+	if err := os.Symlink("util.ts", filepath.Join(tempDir, "src/alias.ts")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	// A linked directory in the middle of a path
+	if err := os.MkdirAll(filepath.Join(tempDir, "pkg"), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.Symlink("../src/lib", filepath.Join(tempDir, "pkg/lib")); err != nil {
+		t.Fatalf("Failed to create symlink: %v", err)
+	}
+	git("add", "-A")
+	git("commit", "-q", "-m", "v1")
+	git("tag", "v1")
+
+	write("src/app.ts", "import { util } from './util';\nimport { other } from './other';\n")
+	write("src/util.ts", "export const util = 2;\n")
+	write("src/other.ts", "export const other = 1;\n")
+	git("rm", "-q", "src/lib/old.ts")
+	git("add", "-A")
+	git("commit", "-q", "-m", "v2")
+	// Uncommitted changes aren't seen at a revision either
+	write("src/util.ts", "export const util = 3;\n")
+
+	revFS, err := newGitRevFS(tempDir, "v1")
+	if err != nil {
+		t.Fatalf("newGitRevFS failed: %v", err)
+	}
+	defer revFS.Close()
+	if err := fstest.TestFS(revFS, "package.json", "src/app.ts", "src/alias.ts", "src/lib/old.ts"); err != nil {
+		t.Errorf("gitRevFS doesn't behave as an fs.FS: %v", err)
+	}
+	// Files are read by one cat-file process rather than one git command each
+	batch := revFS.batch
+	if batch == nil {
+		t.Fatalf("Expected a cat-file process after reading files")
+	}
+	if _, err := fs.ReadFile(revFS, "src/util.ts"); err != nil || revFS.batch != batch {
+		t.Errorf("Expected the cat-file process to be reused, got error %v", err)
+	}
+	fileSystem = newMountFS(tempDir, revFS, fileSystem)
+
+	root, err := FindProjectRoot(filepath.Join(tempDir, "src/app.ts"))
+	if err != nil || root != tempDir {
+		t.Errorf("Expected project root %s, got %s (%v)", tempDir, root, err)
+	}
+
+	processedFiles = make(map[string]struct{})
+	results := make(map[string]string)
+	if err := ProcessFile(filepath.Join(tempDir, "src/app.ts"), tempDir, results); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	expected := map[string]string{
+		"src/app.ts":  "import { util } from './util';\n",
+		"src/util.ts": "export const util = 1;\n",
+	}
+	if len(results) != len(expected) {
+		t.Errorf("Expected %d files, got %d", len(expected), len(results))
+	}
+	for path, content := range expected {
+		if got := results[filepath.Join(tempDir, path)]; got != content {
+			t.Errorf("Expected %s at v1 to be %q, got %q", path, content, got)
+		}
+	}
+
+	// Files deleted since the revision still exist in it, and files added since don't
+	if _, err := statFile(filepath.Join(tempDir, "src/lib/old.ts")); err != nil {
+		t.Errorf("Expected src/lib/old.ts to exist at v1: %v", err)
+	}
+	if content, err := readFile(filepath.Join(tempDir, "pkg/lib/old.ts")); err != nil || string(content) != "export const old = 1;\n" {
+		t.Errorf("Expected pkg/lib/old.ts to be read through the linked directory, got %q (%v)", content, err)
+	}
+	if _, err := statFile(filepath.Join(tempDir, "src/other.ts")); !os.IsNotExist(err) {
+		t.Errorf("Expected src/other.ts not to exist at v1, got %v", err)
+	}
+
+	entries, err := readDir(filepath.Join(tempDir, "src"))
+	if err != nil {
+		t.Fatalf("ReadDir failed: %v", err)
+	}
+	var names []string
+	for _, entry := range entries {
+		names = append(names, entry.Name())
+	}
+	if strings.Join(names, ",") != "alias.ts,app.ts,lib,util.ts" {
+		t.Errorf("Expected src to contain alias.ts, app.ts, lib and util.ts, got %v", names)
+	}
+
+	// Links are followed within the revision
+	content, err := readFile(filepath.Join(tempDir, "src/alias.ts"))
+	if err != nil || string(content) != "export const util = 1;\n" {
+		t.Errorf("Expected src/alias.ts to read src/util.ts at v1, got %q (%v)", content, err)
+	}
+	if resolved, err := realPath(filepath.Join(tempDir, "src/alias.ts")); err != nil || resolved != filepath.Join(tempDir, "src/util.ts") {
+		t.Errorf("Expected src/alias.ts to resolve to src/util.ts, got %s (%v)", resolved, err)
+	}
+
+	if resolved, err := realPath(filepath.Join(tempDir, "pkg/lib/old.ts")); err != nil || resolved != filepath.Join(tempDir, "src/lib/old.ts") {
+		t.Errorf("Expected pkg/lib/old.ts to resolve to src/lib/old.ts, got %s (%v)", resolved, err)
+	}
+
+	matches, _ := globFiles(filepath.Join(tempDir, "src", "*", "*.ts"))
+	if len(matches) != 1 || matches[0] != filepath.Join(tempDir, "src/lib/old.ts") {
+		t.Errorf("Expected glob to match src/lib/old.ts, got %v", matches)
+	}
+
+	var walked []string
+	walkDir(tempDir, func(path string, d fs.DirEntry, err error) error {
+		if err == nil && !d.IsDir() {
+			rel, _ := filepath.Rel(tempDir, path)
+			walked = append(walked, filepath.ToSlash(rel))
+		}
+		return nil
+	})
+	// Links aren't followed by walks, so the linked directory is listed as an entry
+	if strings.Join(walked, ",") != "package.json,pkg/lib,src/alias.ts,src/app.ts,src/lib/old.ts,src/util.ts" {
+		t.Errorf("Unexpected files walked at v1: %v", walked)
+	}
+
+	if _, err := newGitRevFS(tempDir, "no-such-rev"); err == nil {
+		t.Errorf("Expected an error for an unknown revision")
+	}
+}
+
+// TestOptionalValueFlag tests that -changed works with and without a value
+func TestOptionalValueFlag(t *testing.T) {
+	tests := []struct {
+		value string
+		set   bool
+		ref   string
+	}{
+		{"true", true, ""},
+		{"main", true, "main"},
+		{"false", false, ""},
+	}
+
+	for _, test := range tests {
+		var f optionalValueFlag
+		if err := f.Set(test.value); err != nil {
+			t.Fatalf("Set(%q) failed: %v", test.value, err)
+		}
+		if f.set != test.set || f.value != test.ref {
+			t.Errorf("Set(%q): expected set=%v value=%q, got set=%v value=%q", test.value, test.set, test.ref, f.set, f.value)
+		}
+	}
+}
+
+// newTestRepo creates an empty git repository in a temp directory, skipping the test if git
+// isn't installed. It returns the directory and helpers to run git and write files in it.
+func newTestRepo(t *testing.T) (string, func(args ...string), func(path string, content string)) {
+	t.Helper()
+	if _, err := exec.LookPath("git"); err != nil {
+		t.Skip("git not found in PATH")
+	}
+
+	tempDir, err := os.MkdirTemp("", "git-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	t.Cleanup(func() { os.RemoveAll(tempDir) })
+	if tempDir, err = filepath.EvalSymlinks(tempDir); err != nil {
+		t.Fatalf("Failed to resolve temp directory: %v", err)
+	}
+
+	git := func(args ...string) {
+		t.Helper()
+		args = append([]string{"-c", "user.name=test", "-c", "user.email=test@example.com"}, args...)
+		cmd := exec.Command("git", args...)
+		cmd.Dir = tempDir
+		if out, err := cmd.CombinedOutput(); err != nil {
+			t.Fatalf("git %v failed: %v\n%s", args, err, out)
+		}
+	}
+	write := func(path string, content string) {
+		t.Helper()
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	git("init", "-q")
+	return tempDir, git, write
+}
@@ -1,3 +1,7 @@
 module github.com/techtransplant/fixfiles
 
 go 1.24
+
+require github.com/fsnotify/fsnotify v1.10.1
+
+require golang.org/x/sys v0.13.0 // indirect
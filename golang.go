@@ -0,0 +1,210 @@
+package main
+
+import (
+	"go/parser"
+	"go/token"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strconv"
+	"strings"
+)
+
+// goModFile is the subset of a go.mod file used to resolve import paths
+type goModFile struct {
+	dir        string
+	modulePath string
+	// Required module versions keyed by module path
+	requires map[string]string
+	// Replacement directories for modules replaced with a local path
+	replaces map[string]string
+}
+
+// Matches the module, require and replace directives of a go.mod file
+var (
+	goModulePattern  = regexp.MustCompile(`(?m)^module\s+(\S+)`)
+	goRequirePattern = regexp.MustCompile(`(?m)^(?:require\s+)?\s*([\w.\-/~]+)\s+(v[\w.\-+]+)`)
+	goReplacePattern = regexp.MustCompile(`(?m)^(?:replace\s+)?\s*([\w.\-/~]+)(?:\s+v\S+)?\s+=>\s+(\.{1,2}/\S*|/\S+)`)
+)
+
+// Parsed go.mod files keyed by directory; nil marks a directory without one
+var goModFiles = make(map[string]*goModFile)
+
+// goLanguage resolves Go imports through go.mod to the files of the imported packages
+type goLanguage struct{}
+
+func init() {
+	RegisterLanguage(goLanguage{})
+}
+
+// Name identifies the language
+func (goLanguage) Name() string {
+	return "Go"
+}
+
+// Detect reports whether a file is Go source
+func (goLanguage) Detect(fileExt string) bool {
+	return fileExt == ".go"
+}
+
+// ExtractSpecifiers parses the import paths of a Go file
+func (goLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	parsed, err := parser.ParseFile(token.NewFileSet(), file.Path, file.Content, parser.ImportsOnly)
+	if err != nil {
+		return nil
+	}
+
+	var specs []Specifier
+	for _, spec := range parsed.Imports {
+		if importPath, err := strconv.Unquote(spec.Path.Value); err == nil {
+			specs = append(specs, Specifier{Path: importPath})
+		}
+	}
+	return specs
+}
+
+// IsBuiltin reports whether an import path is in the standard library, which has no dot in its first element
+func (goLanguage) IsBuiltin(spec Specifier) bool {
+	return !strings.Contains(strings.SplitN(spec.Path, "/", 2)[0], ".")
+}
+
+// ResolveSpecifier returns the files of an imported package
+func (goLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	if dir := resolveGoPackage(spec.Path, file.Path, file.ProjectRoot); dir != "" {
+		return goPackageFiles(dir)
+	}
+	return nil
+}
+
+// resolveGoPackage finds the directory of an imported package, or "" for modules that shouldn't be followed
+func resolveGoPackage(importPath string, fromFile string, projectRoot string) string {
+	mod := nearestGoMod(filepath.Dir(fromFile), projectRoot)
+	if mod == nil {
+		return ""
+	}
+
+	if importPath == mod.modulePath || strings.HasPrefix(importPath, mod.modulePath+"/") {
+		return filepath.Join(mod.dir, strings.TrimPrefix(importPath, mod.modulePath))
+	}
+
+	modulePath, version := mod.moduleFor(importPath)
+	if modulePath == "" {
+		return ""
+	}
+	subdir := strings.TrimPrefix(importPath, modulePath)
+
+	// Modules replaced with a local directory are part of the project
+	if dir, ok := mod.replaces[modulePath]; ok {
+		return filepath.Join(dir, subdir)
+	}
+
+	if !externalAllowed(modulePath) {
+		return ""
+	}
+
+	vendorDir := filepath.Join(mod.dir, "vendor", importPath)
+	if info, err := statFile(vendorDir); err == nil && info.IsDir() {
+		return vendorDir
+	}
+
+	if modCache := goModCache(); modCache != "" && version != "" {
+		dir := filepath.Join(modCache, escapeGoModulePath(modulePath)+"@"+version, subdir)
+		if info, err := statFile(dir); err == nil && info.IsDir() {
+			return dir
+		}
+	}
+
+	return ""
+}
+
+// moduleFor returns the required or replaced module that provides an import path, preferring the longest match
+func (m *goModFile) moduleFor(importPath string) (string, string) {
+	best, version := "", ""
+	candidates := make(map[string]string)
+	for path, v := range m.requires {
+		candidates[path] = v
+	}
+	for path := range m.replaces {
+		if _, ok := candidates[path]; !ok {
+			candidates[path] = ""
+		}
+	}
+
+	for path, v := range candidates {
+		if (importPath == path || strings.HasPrefix(importPath, path+"/")) && len(path) > len(best) {
+			best, version = path, v
+		}
+	}
+	return best, version
+}
+
+// goPackageFiles returns the non-test Go files of a package directory
+func goPackageFiles(dir string) []string {
+	entries, err := readDir(dir)
+	if err != nil {
+		return nil
+	}
+
+	var files []string
+	for _, entry := range entries {
+		name := entry.Name()
+		if !entry.IsDir() && strings.HasSuffix(name, ".go") && !strings.HasSuffix(name, "_test.go") {
+			files = append(files, filepath.Join(dir, name))
+		}
+	}
+	sort.Strings(files)
+	return files
+}
+
+// nearestGoMod finds and parses the closest go.mod at or above dir
+func nearestGoMod(dir string, projectRoot string) *goModFile {
+	for {
+		if mod, ok := cacheLoad(goModFiles, dir); ok {
+			if mod != nil {
+				return mod
+			}
+		} else if mod := parseGoMod(dir); mod != nil {
+			cacheStore(goModFiles, dir, mod)
+			return mod
+		} else {
+			cacheStore(goModFiles, dir, nil)
+		}
+
+		if dir == projectRoot || filepath.Dir(dir) == dir {
+			return nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// parseGoMod reads the go.mod file in dir, if there is one
+func parseGoMod(dir string) *goModFile {
+	content, err := readFile(filepath.Join(dir, "go.mod"))
+	if err != nil {
+		return nil
+	}
+
+	match := goModulePattern.FindSubmatch(content)
+	if match == nil {
+		return nil
+	}
+
+	mod := &goModFile{
+		dir:        dir,
+		modulePath: string(match[1]),
+		requires:   make(map[string]string),
+		replaces:   make(map[string]string),
+	}
+	for _, match := range goRequirePattern.FindAllSubmatch(content, -1) {
+		mod.requires[string(match[1])] = string(match[2])
+	}
+	for _, match := range goReplacePattern.FindAllSubmatch(content, -1) {
+		target := string(match[2])
+		if !filepath.IsAbs(target) {
+			target = filepath.Join(dir, target)
+		}
+		mod.replaces[string(match[1])] = target
+	}
+
+	return mod
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtractGoImports tests resolving module-local and replaced packages to their files
+func TestExtractGoImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "go-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"go.mod":                    "module example.com/app\n\nrequire example.com/shared v0.0.0\n\nreplace example.com/shared => ./shared\n",
+		"internal/store/db.go":      "package store",
+		"internal/store/sql.go":     "package store",
+		"internal/store/db_test.go": "package store",
+		"shared/log/log.go":         "package log",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	mainPath := filepath.Join(tempDir, "main.go")
+	main := []byte(`package main
+
+import (
+	"fmt"
+	"net/http"
+
+	"example.com/app/internal/store"
+	applog "example.com/shared/log"
+	"github.com/pkg/errors"
+)
+`)
+
+	config = Config{}
+	imports := extractImportsFromContent(mainPath, ".go", main, tempDir)
+	expected := []string{
+		filepath.Join(tempDir, "internal", "store", "db.go"),
+		filepath.Join(tempDir, "internal", "store", "sql.go"),
+		filepath.Join(tempDir, "shared", "log", "log.go"),
+	}
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for i := range expected {
+		if imports[i] != expected[i] {
+			t.Errorf("Import %d: got %s, want %s", i, imports[i], expected[i])
+		}
+	}
+}
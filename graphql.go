@@ -0,0 +1,160 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// GraphQL document extensions
+var graphqlExtensions = []string{".graphql", ".gql"}
+
+// Matches #import directives, as used by graphql-import and graphql-tag/loader
+var graphqlImportPattern = regexp.MustCompile(`(?m)^[ \t]*#[ \t]*import[ \t]+["']([^"']+)["']`)
+
+// Matches fragment spreads; inline fragments (... on Type) are filtered out by name
+var graphqlSpreadPattern = regexp.MustCompile(`\.\.\.\s*([_A-Za-z]\w*)`)
+
+// Matches fragment definitions
+var graphqlFragmentPattern = regexp.MustCompile(`\bfragment\s+([_A-Za-z]\w*)\s+on\b`)
+
+// Matches GraphQL comments
+var graphqlCommentPattern = regexp.MustCompile(`(?m)#.*$`)
+
+// Files defining each fragment, per project root
+var graphqlFragments = make(map[string]map[string]string)
+
+// graphqlLanguage follows #import directives and links fragment spreads to the files defining them
+type graphqlLanguage struct{}
+
+func init() {
+	RegisterLanguage(graphqlLanguage{})
+}
+
+// Name identifies the language
+func (graphqlLanguage) Name() string {
+	return "GraphQL"
+}
+
+// Detect reports whether a file is a GraphQL document
+func (graphqlLanguage) Detect(fileExt string) bool {
+	return containsString(graphqlExtensions, fileExt)
+}
+
+// ExtractSpecifiers returns the #import paths and the fragments spread but not defined in the document
+func (graphqlLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+	for _, match := range graphqlImportPattern.FindAllSubmatch(file.Content, -1) {
+		specs = append(specs, Specifier{Path: string(match[1]), Kind: "import"})
+	}
+	for _, name := range graphqlMissingFragments([]string{string(file.Content)}) {
+		specs = append(specs, Specifier{Path: name, Kind: "fragment"})
+	}
+	return specs
+}
+
+// IsBuiltin is always false; GraphQL has no standard library
+func (graphqlLanguage) IsBuiltin(spec Specifier) bool {
+	return false
+}
+
+// ResolveSpecifier resolves an #import relative to the document, or a fragment through the project's fragment index
+func (graphqlLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	if spec.Kind == "fragment" {
+		return []string{graphqlFragmentFile(spec.Path, file.ProjectRoot)}
+	}
+
+	// #import paths are relative to the document even without a leading ./
+	importPath := spec.Path
+	if !strings.HasPrefix(importPath, ".") && !strings.HasPrefix(importPath, "/") {
+		importPath = "./" + importPath
+	}
+	resolved, err := ResolveImportPath(importPath, filepath.Dir(file.Path), file.ProjectRoot)
+	if err != nil {
+		return nil
+	}
+	return []string{resolved}
+}
+
+// graphqlMissingFragments returns the fragments spread in a set of documents that none of them define
+func graphqlMissingFragments(documents []string) []string {
+	defined := make(map[string]struct{})
+	var spreads []string
+
+	for _, document := range documents {
+		code := graphqlCommentPattern.ReplaceAllString(document, "")
+		for _, match := range graphqlFragmentPattern.FindAllStringSubmatch(code, -1) {
+			defined[match[1]] = struct{}{}
+		}
+		for _, match := range graphqlSpreadPattern.FindAllStringSubmatch(code, -1) {
+			if match[1] != "on" && !containsString(spreads, match[1]) {
+				spreads = append(spreads, match[1])
+			}
+		}
+	}
+
+	var missing []string
+	for _, name := range spreads {
+		if _, ok := defined[name]; !ok {
+			missing = append(missing, name)
+		}
+	}
+	return missing
+}
+
+// graphqlFragmentFile returns the file defining a fragment, or "" if no project file does
+func graphqlFragmentFile(name string, projectRoot string) string {
+	fragments, ok := cacheLoad(graphqlFragments, projectRoot)
+	if !ok {
+		fragments = indexGraphQLFragments(projectRoot)
+		cacheStore(graphqlFragments, projectRoot, fragments)
+	}
+	return fragments[name]
+}
+
+// indexGraphQLFragments maps the fragments defined in the project's GraphQL documents and in gql
+// templates of JavaScript and TypeScript files to the files defining them
+func indexGraphQLFragments(projectRoot string) map[string]string {
+	fragments := make(map[string]string)
+
+	walkDir(projectRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return nil
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if path != projectRoot && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+
+		ext := fileExtension(path)
+		isGraphQL := containsString(graphqlExtensions, ext)
+		if !isGraphQL && !containsString(javaScriptExtensions, ext) {
+			return nil
+		}
+		content, err := readFile(path)
+		if err != nil || !strings.Contains(string(content), "fragment") {
+			return nil
+		}
+
+		documents := []string{string(content)}
+		if !isGraphQL {
+			documents = lexJS(content, ext).graphqlDocuments
+		}
+		for _, document := range documents {
+			code := graphqlCommentPattern.ReplaceAllString(document, "")
+			for _, match := range graphqlFragmentPattern.FindAllStringSubmatch(code, -1) {
+				// The first definition wins, as walking is in lexical order
+				if _, ok := fragments[match[1]]; !ok {
+					fragments[match[1]] = path
+				}
+			}
+		}
+		return nil
+	})
+
+	return fragments
+}
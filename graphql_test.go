@@ -0,0 +1,82 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestGraphQLImports tests following #import directives and linking fragment spreads to their definitions
+func TestGraphQLImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "graphql-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"src/queries/user.graphql":     "#import \"../fragments/avatar.graphql\"\n# ...Commented\nquery User { user { ...UserFields ...Avatar ... on Admin { role } } }\n",
+		"src/fragments/avatar.graphql": "fragment Avatar on User { url }",
+		"src/fragments/user.gql":       "fragment UserFields on User { id name }",
+		"src/api/posts.ts": "import { gql } from '@apollo/client';\n" +
+			"const POST = gql`\n  fragment PostFields on Post { id }\n`;\n" +
+			"export const QUERY = gql`\n  query Posts { posts { ...PostFields author { ...UserFields } } }\n  ${POST}\n`;\n" +
+			"const text = `...NotAFragment`;\n",
+		"src/api/comments.ts":                "export const COMMENT = graphql`fragment CommentFields on Comment { body }`;",
+		"src/api/feed.ts":                    "export const FEED = gql`query Feed { feed { ...CommentFields } }`;",
+		"node_modules/lib/fragments.graphql": "fragment UserFields on User { id }",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		entry    string
+		expected []string
+	}{
+		{
+			name:     "Imports and spreads in a document",
+			entry:    "src/queries/user.graphql",
+			expected: []string{"src/queries/user.graphql", "src/fragments/avatar.graphql", "src/fragments/user.gql"},
+		},
+		{
+			name:     "Fragments defined in the same file are not linked",
+			entry:    "src/api/posts.ts",
+			expected: []string{"src/api/posts.ts", "src/fragments/user.gql"},
+		},
+		{
+			name:     "Fragments defined in other gql templates",
+			entry:    "src/api/feed.ts",
+			expected: []string{"src/api/feed.ts", "src/api/comments.ts"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			processedFiles = make(map[string]struct{})
+			graphqlFragments = make(map[string]map[string]string)
+
+			results := make(map[string]string)
+			if err := ProcessFile(filepath.Join(tempDir, tc.entry), tempDir, results); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+
+			if len(results) != len(tc.expected) {
+				t.Fatalf("Expected %d files, got %d: %v", len(tc.expected), len(results), keys(results))
+			}
+			for _, path := range tc.expected {
+				if _, ok := results[filepath.Join(tempDir, path)]; !ok {
+					t.Errorf("Expected file %s not found in results", path)
+				}
+			}
+		})
+	}
+}
@@ -0,0 +1,185 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strconv"
+	"strings"
+	"time"
+)
+
+// Recent commits of collected files, shown after their contents
+var fileHistory = make(map[string][]string)
+
+// Blame of the lines around the error location, keyed by file
+var fileBlame = make(map[string]*blameSummary)
+
+// blameSummary is the blame of a range of lines in a file
+type blameSummary struct {
+	Start, End int
+	Lines      []string
+}
+
+// IncludeHistory records the last n commits touching each collected project file, up to rev when
+// the files were collected at a revision
+func IncludeHistory(projectRoot string, results map[string]string, n int, rev string) {
+	repoRoot, err := gitRepoRootFor(projectRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: not adding history, %s is not in a git repository\n", projectRoot)
+		return
+	}
+
+	var paths []string
+	for path := range results {
+		if _, external := externalFiles[path]; !external {
+			paths = append(paths, path)
+		}
+	}
+	sort.Strings(paths)
+
+	for _, path := range paths {
+		commits, err := recentCommits(repoRoot, path, n, rev)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not read history of %s: %v\n", path, err)
+			continue
+		}
+		if len(commits) > 0 {
+			fileHistory[path] = commits
+		}
+	}
+}
+
+// recentCommits returns the last n commits touching a file as "hash date subject" lines, starting
+// from rev, or HEAD if it's empty
+func recentCommits(repoRoot string, path string, n int, rev string) ([]string, error) {
+	rel, err := filepath.Rel(repoRoot, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return nil, nil
+	}
+
+	args := []string{"log", "-n", strconv.Itoa(n), "--format=%h %ad %s", "--date=short"}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	out, err := gitOutput(repoRoot, append(args, "--", filepath.ToSlash(rel))...)
+	if err != nil {
+		return nil, err
+	}
+
+	var commits []string
+	for _, line := range strings.Split(out, "\n") {
+		if line = strings.TrimSpace(line); line != "" {
+			commits = append(commits, line)
+		}
+	}
+	return commits, nil
+}
+
+// parseLocation splits an error location like src/app.ts:42 into its path and line
+func parseLocation(location string) (string, int, error) {
+	sep := strings.LastIndex(location, ":")
+	if sep <= 0 {
+		return "", 0, fmt.Errorf("expected PATH:LINE, got %q", location)
+	}
+	line, err := strconv.Atoi(location[sep+1:])
+	if err != nil || line < 1 {
+		return "", 0, fmt.Errorf("invalid line number in %q", location)
+	}
+	return location[:sep], line, nil
+}
+
+// IncludeBlame records who last changed the lines within context lines of line in a file, as of
+// rev when the files were collected at a revision
+func IncludeBlame(projectRoot string, path string, line int, context int, rev string) error {
+	repoRoot, err := gitRepoRootFor(projectRoot)
+	if err != nil {
+		return fmt.Errorf("%s is not in a git repository", projectRoot)
+	}
+	rel, err := filepath.Rel(repoRoot, path)
+	if err != nil || strings.HasPrefix(rel, "..") {
+		return fmt.Errorf("%s is outside the git repository", path)
+	}
+
+	start := line - context
+	if start < 1 {
+		start = 1
+	}
+	end := line + context
+
+	// Clamp the range to the file, as blame rejects lines past the end. The file is read from the
+	// revision being collected, if there is one.
+	content, err := readFile(path)
+	if err != nil {
+		return err
+	}
+	lineCount := strings.Count(string(content), "\n")
+	if !strings.HasSuffix(string(content), "\n") {
+		lineCount++
+	}
+	if end > lineCount {
+		end = lineCount
+	}
+	if start > end {
+		return fmt.Errorf("line %d is past the end of %s", line, path)
+	}
+
+	args := []string{"blame", "--porcelain", "-L", fmt.Sprintf("%d,%d", start, end)}
+	if rev != "" {
+		args = append(args, rev)
+	}
+	out, err := gitOutput(repoRoot, append(args, "--", filepath.ToSlash(rel))...)
+	if err != nil {
+		return err
+	}
+
+	fileBlame[filepath.Clean(path)] = &blameSummary{Start: start, End: end, Lines: parseBlame(out, line)}
+	return nil
+}
+
+// parseBlame turns git blame --porcelain output into one "line hash date author | code" entry per
+// line, marking the error line with >
+func parseBlame(porcelain string, errorLine int) []string {
+	type commit struct{ hash, date, author string }
+	commits := make(map[string]*commit)
+
+	var lines []string
+	var current *commit
+	var lineNumber int
+	for _, line := range strings.Split(porcelain, "\n") {
+		if strings.HasPrefix(line, "\t") {
+			marker := " "
+			if lineNumber == errorLine {
+				marker = ">"
+			}
+			lines = append(lines, fmt.Sprintf("%s%5d %s %s %-16s | %s", marker, lineNumber, current.hash, current.date, current.author, line[1:]))
+			continue
+		}
+
+		fields := strings.Fields(line)
+		if len(fields) >= 3 && len(fields[0]) == 40 {
+			// Header of a line group: hash, original line, final line
+			sha := fields[0]
+			if commits[sha] == nil {
+				commits[sha] = &commit{hash: sha[:7]}
+			}
+			current = commits[sha]
+			lineNumber, _ = strconv.Atoi(fields[2])
+			continue
+		}
+		if current == nil || len(fields) < 2 {
+			continue
+		}
+		switch fields[0] {
+		case "author":
+			current.author = strings.TrimPrefix(line, "author ")
+		case "author-time":
+			if seconds, err := strconv.ParseInt(fields[1], 10, 64); err == nil {
+				current.date = time.Unix(seconds, 0).UTC().Format("2006-01-02")
+			}
+		}
+	}
+
+	return lines
+}
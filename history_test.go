@@ -0,0 +1,204 @@
+package main
+
+import (
+	"io/fs"
+	"path/filepath"
+	"strings"
+	"testing"
+)
+
+// TestIncludeHistory tests listing the recent commits of collected files
+func TestIncludeHistory(t *testing.T) {
+	tempDir, git, write := newTestRepo(t)
+	defer func() { fileHistory = make(map[string][]string) }()
+
+	write("package.json", `{"name": "app"}`)
+	write("src/app.js", "import { util } from './util';\n")
+	write("src/util.js", "export const util = 1;\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "Add app")
+	write("src/util.js", "export const util = 2;\n")
+	git("commit", "-q", "-am", "Change util")
+	write("src/util.js", "export const util = 3;\n")
+	git("commit", "-q", "-am", "Change util again")
+
+	processedFiles = make(map[string]struct{})
+	fileHistory = make(map[string][]string)
+	results := make(map[string]string)
+	if err := ProcessFile(filepath.Join(tempDir, "src/app.js"), tempDir, results); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	IncludeHistory(tempDir, results, 2, "")
+
+	tests := []struct {
+		path     string
+		subjects []string
+	}{
+		{"src/app.js", []string{"Add app"}},
+		{"src/util.js", []string{"Change util again", "Change util"}},
+	}
+	for _, test := range tests {
+		commits := fileHistory[filepath.Join(tempDir, test.path)]
+		if len(commits) != len(test.subjects) {
+			t.Errorf("Expected %d commits for %s, got %v", len(test.subjects), test.path, commits)
+			continue
+		}
+		for i, subject := range test.subjects {
+			// Each commit is listed as hash, date and subject
+			fields := strings.SplitN(commits[i], " ", 3)
+			if len(fields) != 3 || len(fields[1]) != len("2006-01-02") || fields[2] != subject {
+				t.Errorf("Expected commit %d of %s to be %q, got %q", i, test.path, subject, commits[i])
+			}
+		}
+	}
+
+	output := FormatResults(results)
+	if !strings.Contains(output, "{{ BEGIN HISTORY OF "+filepath.Join(tempDir, "src/util.js")+" }}") {
+		t.Errorf("Expected the history of src/util.js in output")
+	}
+}
+
+// TestIncludeBlame tests blaming the lines around an error location
+func TestIncludeBlame(t *testing.T) {
+	tempDir, git, write := newTestRepo(t)
+	defer func() { fileBlame = make(map[string]*blameSummary) }()
+
+	lines := make([]string, 20)
+	for i := range lines {
+		lines[i] = "line"
+	}
+	write("main.go", strings.Join(lines, "\n")+"\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "Add main")
+	lines[9] = "changed"
+	write("main.go", strings.Join(lines, "\n")+"\n")
+	git("-c", "user.name=Other Person", "commit", "-q", "-am", "Change line 10")
+
+	tests := []struct {
+		name       string
+		line       int
+		start, end int
+	}{
+		{"middle of file", 10, 7, 13},
+		{"start of file", 2, 1, 5},
+		{"end of file", 19, 16, 20},
+	}
+	for _, test := range tests {
+		t.Run(test.name, func(t *testing.T) {
+			fileBlame = make(map[string]*blameSummary)
+			path := filepath.Join(tempDir, "main.go")
+			if err := IncludeBlame(tempDir, path, test.line, 3, ""); err != nil {
+				t.Fatalf("IncludeBlame failed: %v", err)
+			}
+			blame := fileBlame[path]
+			if blame == nil {
+				t.Fatalf("Expected a blame for main.go")
+			}
+			if blame.Start != test.start || blame.End != test.end {
+				t.Errorf("Expected lines %d-%d, got %d-%d", test.start, test.end, blame.Start, blame.End)
+			}
+			if len(blame.Lines) != test.end-test.start+1 {
+				t.Fatalf("Expected %d blamed lines, got %v", test.end-test.start+1, blame.Lines)
+			}
+			marked := blame.Lines[test.line-test.start]
+			if !strings.HasPrefix(marked, ">") {
+				t.Errorf("Expected the error line to be marked, got %q", marked)
+			}
+		})
+	}
+
+	// The changed line is attributed to the later commit and its author
+	fileBlame = make(map[string]*blameSummary)
+	path := filepath.Join(tempDir, "main.go")
+	if err := IncludeBlame(tempDir, path, 10, 1, ""); err != nil {
+		t.Fatalf("IncludeBlame failed: %v", err)
+	}
+	blame := fileBlame[path]
+	if !strings.Contains(blame.Lines[1], "Other Person") || !strings.HasSuffix(blame.Lines[1], "| changed") {
+		t.Errorf("Expected line 10 to be blamed on Other Person, got %q", blame.Lines[1])
+	}
+	if !strings.Contains(blame.Lines[0], "test ") {
+		t.Errorf("Expected line 9 to be blamed on test, got %q", blame.Lines[0])
+	}
+
+	if err := IncludeBlame(tempDir, path, 50, 3, ""); err == nil {
+		t.Errorf("Expected an error for a line past the end of the file")
+	}
+}
+
+// TestParseLocation tests splitting an error location into path and line
+func TestParseLocation(t *testing.T) {
+	tests := []struct {
+		location string
+		path     string
+		line     int
+		wantErr  bool
+	}{
+		{"src/app.ts:42", "src/app.ts", 42, false},
+		{`C:\src\app.ts:7`, `C:\src\app.ts`, 7, false},
+		{"src/app.ts", "", 0, true},
+		{"src/app.ts:0", "", 0, true},
+		{"src/app.ts:abc", "", 0, true},
+	}
+
+	for _, test := range tests {
+		path, line, err := parseLocation(test.location)
+		if (err != nil) != test.wantErr {
+			t.Errorf("parseLocation(%q): expected error %v, got %v", test.location, test.wantErr, err)
+			continue
+		}
+		if path != test.path || line != test.line {
+			t.Errorf("parseLocation(%q): expected %s:%d, got %s:%d", test.location, test.path, test.line, path, line)
+		}
+	}
+}
+
+// TestHistoryAndBlameAtRevision tests that history and blame describe the revision being collected
+func TestHistoryAndBlameAtRevision(t *testing.T) {
+	tempDir, git, write := newTestRepo(t)
+	defer func(original fs.FS) { fileSystem = original }(fileSystem)
+	defer func() {
+		fileHistory = make(map[string][]string)
+		fileBlame = make(map[string]*blameSummary)
+	}()
+
+	write("package.json", `{"name": "app"}`)
+	write("src/app.js", "import { util } from './util';\n")
+	write("src/util.js", "export const util = 1;\n")
+	git("add", "-A")
+	git("commit", "-q", "-m", "Add app")
+	write("src/util.js", "export const util = 2;\n")
+	git("commit", "-q", "-am", "Change util")
+	git("rm", "-q", "src/util.js")
+	git("commit", "-q", "-m", "Remove util")
+
+	revFS, err := newGitRevFS(tempDir, "HEAD~1")
+	if err != nil {
+		t.Fatalf("newGitRevFS failed: %v", err)
+	}
+	defer revFS.Close()
+	fileSystem = newMountFS(tempDir, revFS, fileSystem)
+
+	resetCollection()
+	results := make(map[string]string)
+	if err := ProcessFile(filepath.Join(tempDir, "src/app.js"), tempDir, results); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+
+	// Commits after the revision aren't listed
+	IncludeHistory(tempDir, results, 5, "HEAD~1")
+	commits := fileHistory[filepath.Join(tempDir, "src/util.js")]
+	if len(commits) != 2 || !strings.HasSuffix(commits[0], " Change util") || !strings.HasSuffix(commits[1], " Add app") {
+		t.Errorf("Expected the history of util.js up to HEAD~1, got %v", commits)
+	}
+
+	// A file deleted from the working tree is blamed at the revision
+	path := filepath.Join(tempDir, "src/util.js")
+	if err := IncludeBlame(tempDir, path, 1, 2, "HEAD~1"); err != nil {
+		t.Fatalf("IncludeBlame failed: %v", err)
+	}
+	blame := fileBlame[path]
+	if blame == nil || len(blame.Lines) != 1 || !strings.HasSuffix(blame.Lines[0], "| export const util = 2;") {
+		t.Errorf("Expected the blame of util.js at HEAD~1, got %+v", blame)
+	}
+}
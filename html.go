@@ -0,0 +1,317 @@
+package main
+
+import (
+	"bytes"
+	"fmt"
+	"html"
+	"path/filepath"
+	"strings"
+)
+
+// htmlTag is a start tag found by the tokenizer
+type htmlTag struct {
+	name  string
+	attrs map[string]string
+	// Raw text of script and style elements
+	text []byte
+}
+
+// Elements whose contents are raw text rather than markup
+var rawTextElements = map[string]struct{}{
+	"script":   {},
+	"style":    {},
+	"textarea": {},
+	"title":    {},
+}
+
+// Binary and media files that are only included when assets are requested
+var assetExtensions = map[string]struct{}{
+	".png":   {},
+	".jpg":   {},
+	".jpeg":  {},
+	".gif":   {},
+	".webp":  {},
+	".avif":  {},
+	".bmp":   {},
+	".ico":   {},
+	".svg":   {},
+	".mp4":   {},
+	".webm":  {},
+	".mp3":   {},
+	".wav":   {},
+	".ogg":   {},
+	".woff":  {},
+	".woff2": {},
+	".ttf":   {},
+	".otf":   {},
+	".eot":   {},
+}
+
+// link rel values that load code or data the page depends on
+var htmlCodeLinkRels = []string{"stylesheet", "modulepreload", "preload", "prefetch", "import", "manifest"}
+
+// htmlLanguage finds the local files a page references through script, link, img, source and
+// iframe tags, and the imports of inline module scripts and styles
+type htmlLanguage struct{}
+
+func init() {
+	RegisterLanguage(htmlLanguage{})
+}
+
+// Name identifies the language
+func (htmlLanguage) Name() string {
+	return "HTML"
+}
+
+// Detect reports whether a file is an HTML page
+func (htmlLanguage) Detect(fileExt string) bool {
+	return fileExt == ".html" || fileExt == ".htm"
+}
+
+// ExtractSpecifiers returns the URLs of referenced scripts, stylesheets and frames, of images,
+// fonts and media when assets are requested, and inline module scripts and styles
+func (htmlLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+	addRef := func(ref string) {
+		if ref != "" {
+			specs = append(specs, Specifier{Path: ref})
+		}
+	}
+	addBlock := func(ext string, content []byte) {
+		specs = append(specs, Specifier{Block: &SourceFile{Path: file.Path, Ext: ext, Content: content, ProjectRoot: file.ProjectRoot}})
+	}
+
+	for _, tag := range tokenizeHTML(file.Content) {
+		switch tag.name {
+		case "script":
+			if src, ok := tag.attrs["src"]; ok {
+				addRef(src)
+			} else if strings.EqualFold(tag.attrs["type"], "module") {
+				// Inline module scripts can import other modules
+				addBlock(".js", tag.text)
+			}
+		case "style":
+			addBlock(".css", tag.text)
+		case "link":
+			rels := strings.Fields(strings.ToLower(tag.attrs["rel"]))
+			for _, rel := range rels {
+				if containsString(htmlCodeLinkRels, rel) {
+					addRef(tag.attrs["href"])
+					break
+				}
+				if strings.Contains(rel, "icon") && config.IncludeAssets {
+					addRef(tag.attrs["href"])
+					break
+				}
+			}
+		case "iframe":
+			addRef(tag.attrs["src"])
+		case "img", "source", "video", "audio", "track":
+			if !config.IncludeAssets {
+				continue
+			}
+			for _, attr := range []string{"src", "poster"} {
+				addRef(tag.attrs[attr])
+			}
+			for _, ref := range parseSrcset(tag.attrs["srcset"]) {
+				addRef(ref)
+			}
+		}
+	}
+
+	return specs
+}
+
+// IsBuiltin is always false; absolute URLs are skipped while resolving
+func (htmlLanguage) IsBuiltin(spec Specifier) bool {
+	return false
+}
+
+// ResolveSpecifier resolves a URL to a local file
+func (htmlLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	if resolved, ok := resolveHTMLReference(spec.Path, filepath.Dir(file.Path), file.ProjectRoot); ok {
+		return []string{resolved}
+	}
+	return nil
+}
+
+// resolveHTMLReference resolves a URL in a page to a local file, ignoring absolute URLs and data URIs
+func resolveHTMLReference(ref string, fileDir string, projectRoot string) (string, bool) {
+	ref = strings.TrimSpace(ref)
+	if ref == "" || isExternalURL(ref) || strings.HasPrefix(ref, "#") ||
+		strings.HasPrefix(ref, "mailto:") || strings.HasPrefix(ref, "javascript:") ||
+		strings.Contains(ref, "{{") || strings.Contains(ref, "{%") {
+		return "", false
+	}
+
+	// Drop query strings and fragments such as app.js?v=3
+	if i := strings.IndexAny(ref, "?#"); i >= 0 {
+		ref = ref[:i]
+	}
+
+	// URLs without a leading ./ or / are relative to the page, not bare module names
+	if !strings.HasPrefix(ref, ".") && !strings.HasPrefix(ref, "/") {
+		ref = "./" + ref
+	}
+
+	resolved, err := ResolveImportPath(ref, fileDir, projectRoot)
+	if err != nil {
+		return "", false
+	}
+	return resolved, true
+}
+
+// parseSrcset returns the URLs of a srcset attribute, e.g. "a.png 1x, b.png 2x"
+func parseSrcset(srcset string) []string {
+	var urls []string
+	for _, candidate := range strings.Split(srcset, ",") {
+		if fields := strings.Fields(candidate); len(fields) > 0 {
+			urls = append(urls, fields[0])
+		}
+	}
+	return urls
+}
+
+// isAssetFile reports whether a path is a binary or media asset
+func isAssetFile(path string) bool {
+	_, ok := assetExtensions[strings.ToLower(filepath.Ext(path))]
+	return ok
+}
+
+// describeAsset returns the text included for an asset: SVG source as-is, other assets as a placeholder
+func describeAsset(path string, content []byte) string {
+	if strings.ToLower(filepath.Ext(path)) == ".svg" {
+		return string(content)
+	}
+	return fmt.Sprintf("[binary asset, %d bytes]\n", len(content))
+}
+
+// tokenizeHTML returns the start tags of a document with their attributes, skipping comments,
+// doctypes and end tags. Attributes may appear in any order and with any quoting.
+func tokenizeHTML(content []byte) []htmlTag {
+	var tags []htmlTag
+
+	i := 0
+	for i < len(content) {
+		lt := bytes.IndexByte(content[i:], '<')
+		if lt < 0 {
+			break
+		}
+		i += lt
+
+		rest := content[i:]
+		switch {
+		case bytes.HasPrefix(rest, []byte("<!--")):
+			end := bytes.Index(rest[4:], []byte("-->"))
+			if end < 0 {
+				return tags
+			}
+			i += 4 + end + 3
+			continue
+		case len(rest) > 1 && (rest[1] == '!' || rest[1] == '?' || rest[1] == '/'):
+			end := bytes.IndexByte(rest, '>')
+			if end < 0 {
+				return tags
+			}
+			i += end + 1
+			continue
+		case len(rest) < 2 || !isASCIILetter(rest[1]):
+			i++
+			continue
+		}
+
+		tag, n := parseStartTag(rest)
+		i += n
+
+		if _, raw := rawTextElements[tag.name]; raw {
+			end := bytes.Index(bytes.ToLower(content[i:]), []byte("</"+tag.name))
+			if end < 0 {
+				end = len(content) - i
+			}
+			tag.text = content[i : i+end]
+			i += end
+		}
+
+		tags = append(tags, tag)
+	}
+
+	return tags
+}
+
+// parseStartTag parses a start tag at the beginning of data and returns it with the number of bytes consumed
+func parseStartTag(data []byte) (htmlTag, int) {
+	tag := htmlTag{attrs: make(map[string]string)}
+
+	i := 1
+	start := i
+	for i < len(data) && !isHTMLSpace(data[i]) && data[i] != '>' && data[i] != '/' {
+		i++
+	}
+	tag.name = strings.ToLower(string(data[start:i]))
+
+	for i < len(data) {
+		// Skip whitespace and stray slashes between attributes
+		for i < len(data) && (isHTMLSpace(data[i]) || data[i] == '/') {
+			i++
+		}
+		if i >= len(data) {
+			break
+		}
+		if data[i] == '>' {
+			i++
+			break
+		}
+
+		start = i
+		for i < len(data) && !isHTMLSpace(data[i]) && data[i] != '=' && data[i] != '>' && data[i] != '/' {
+			i++
+		}
+		name := strings.ToLower(string(data[start:i]))
+
+		for i < len(data) && isHTMLSpace(data[i]) {
+			i++
+		}
+		value := ""
+		if i < len(data) && data[i] == '=' {
+			i++
+			for i < len(data) && isHTMLSpace(data[i]) {
+				i++
+			}
+			if i < len(data) && (data[i] == '"' || data[i] == '\'') {
+				quote := data[i]
+				i++
+				start = i
+				for i < len(data) && data[i] != quote {
+					i++
+				}
+				value = string(data[start:i])
+				if i < len(data) {
+					i++
+				}
+			} else {
+				start = i
+				for i < len(data) && !isHTMLSpace(data[i]) && data[i] != '>' {
+					i++
+				}
+				value = string(data[start:i])
+			}
+		}
+
+		// The first occurrence of an attribute wins, as in browsers
+		if _, exists := tag.attrs[name]; !exists && name != "" {
+			tag.attrs[name] = html.UnescapeString(value)
+		}
+	}
+
+	return tag, i
+}
+
+// isHTMLSpace reports whether a byte is HTML whitespace
+func isHTMLSpace(c byte) bool {
+	return c == ' ' || c == '\t' || c == '\n' || c == '\r' || c == '\f'
+}
+
+// isASCIILetter reports whether a byte is an ASCII letter
+func isASCIILetter(c byte) bool {
+	return (c >= 'a' && c <= 'z') || (c >= 'A' && c <= 'Z')
+}
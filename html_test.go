@@ -0,0 +1,190 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtractHTMLImports tests attribute-order independent extraction and local-only filtering
+func TestExtractHTMLImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "html-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := []string{"main.js", "inline-dep.js", "css/site.css", "embed.html", "img/logo.png", "img/logo@2x.png"}
+	for _, path := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(""), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	pagePath := filepath.Join(tempDir, "index.html")
+	page := []byte(`<!DOCTYPE html>
+<html>
+<head>
+  <link href="css/site.css?v=2" rel="stylesheet">
+  <link rel="preconnect" href="https://fonts.example.com">
+  <script defer type="module" src="./main.js"></script>
+  <script src="https://cdn.example.com/react.js"></script>
+  <!-- <script src="./commented-out.js"></script> -->
+</head>
+<body>
+  <img alt="logo" srcset="img/logo.png 1x, img/logo@2x.png 2x" src='img/logo.png'>
+  <img src="data:image/png;base64,AAAA">
+  <iframe title="embed" src=embed.html></iframe>
+  <script type="module">
+    import { start } from './inline-dep.js';
+    const html = "<script src='./not-a-tag.js'>";
+  </script>
+</body>
+</html>
+`)
+
+	config = Config{}
+	imports := extractImportsFromContent(pagePath, ".html", page, tempDir)
+	expected := []string{"css/site.css", "main.js", "embed.html", "inline-dep.js"}
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for i, path := range expected {
+		if imports[i] != filepath.Join(tempDir, path) {
+			t.Errorf("Import %d: got %s, want %s", i, imports[i], filepath.Join(tempDir, path))
+		}
+	}
+
+	// Images are only followed when assets are requested
+	config = Config{IncludeAssets: true}
+	defer func() { config = Config{} }()
+	imports = extractImportsFromContent(pagePath, ".html", page, tempDir)
+	for _, path := range []string{"img/logo.png", "img/logo@2x.png"} {
+		if !containsString(imports, filepath.Join(tempDir, path)) {
+			t.Errorf("Expected to find %s in imports when including assets", path)
+		}
+	}
+}
+
+// TestTokenizeHTML tests that attributes are parsed regardless of order and quoting
+func TestTokenizeHTML(t *testing.T) {
+	tags := tokenizeHTML([]byte(`<LINK media=print REL='stylesheet' href="a&amp;b.css"/><p>`))
+	if len(tags) != 2 {
+		t.Fatalf("Expected 2 tags, got %d", len(tags))
+	}
+
+	link := tags[0]
+	if link.name != "link" {
+		t.Errorf("Expected tag name link, got %s", link.name)
+	}
+	expected := map[string]string{"media": "print", "rel": "stylesheet", "href": "a&b.css"}
+	for name, value := range expected {
+		if link.attrs[name] != value {
+			t.Errorf("Attribute %s: got %q, want %q", name, link.attrs[name], value)
+		}
+	}
+}
+
+// TestProcessHTMLFiles tests that .html and .htm pages are collected, as entry files and as links
+func TestProcessHTMLFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "html-process-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer resetCollection()
+
+	files := map[string]string{
+		"package.json": `{"name": "site"}`,
+		"index.html":   "<html><body><iframe src=\"legacy.htm\"></iframe></body></html>\n",
+		"legacy.htm":   "<html><head><script src=\"legacy.js\"></script></head></html>\n",
+		"legacy.js":    "console.log('legacy');\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	testCases := []struct {
+		entry    string
+		expected []string
+	}{
+		{"index.html", []string{"index.html", "legacy.htm", "legacy.js"}},
+		{"legacy.htm", []string{"legacy.htm", "legacy.js"}},
+	}
+
+	for _, tc := range testCases {
+		resetCollection()
+		results := make(map[string]string)
+		if err := ProcessFile(filepath.Join(tempDir, tc.entry), tempDir, results); err != nil {
+			t.Fatalf("%s: ProcessFile failed: %v", tc.entry, err)
+		}
+		if len(results) != len(tc.expected) {
+			t.Errorf("%s: expected %d files, got %d: %v", tc.entry, len(tc.expected), len(results), resultPaths(results))
+		}
+		for _, path := range tc.expected {
+			if _, ok := results[filepath.Join(tempDir, path)]; !ok {
+				t.Errorf("%s: expected %s to be collected", tc.entry, path)
+			}
+		}
+	}
+}
+
+// TestProcessImportedAssets tests that imported images are skipped unless assets are requested,
+// and are then described rather than included as binary content
+func TestProcessImportedAssets(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "asset-process-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer resetCollection()
+	defer func() { config = Config{} }()
+
+	files := map[string]string{
+		"package.json": `{"name": "app"}`,
+		"app.js":       "import logo from './logo.png';\nimport icon from './icon.svg';\n",
+		"logo.png":     "\x89PNG\r\n\x1a\n",
+		"icon.svg":     "<svg></svg>\n",
+	}
+	for path, content := range files {
+		if err := os.WriteFile(filepath.Join(tempDir, path), []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	testCases := []struct {
+		includeAssets bool
+		expected      map[string]string
+	}{
+		{false, map[string]string{"app.js": files["app.js"]}},
+		{true, map[string]string{
+			"app.js":   files["app.js"],
+			"logo.png": "[binary asset, 8 bytes]\n",
+			"icon.svg": "<svg></svg>\n",
+		}},
+	}
+
+	for _, tc := range testCases {
+		config = Config{IncludeAssets: tc.includeAssets}
+		resetCollection()
+		results := make(map[string]string)
+		if err := ProcessFile(filepath.Join(tempDir, "app.js"), tempDir, results); err != nil {
+			t.Fatalf("ProcessFile failed: %v", err)
+		}
+		if len(results) != len(tc.expected) {
+			t.Errorf("includeAssets=%v: expected %d files, got %v", tc.includeAssets, len(tc.expected), resultPaths(results))
+		}
+		for path, content := range tc.expected {
+			if got, ok := results[filepath.Join(tempDir, path)]; !ok || got != content {
+				t.Errorf("includeAssets=%v: expected %s to be collected as %q, got %q", tc.includeAssets, path, content, got)
+			}
+		}
+	}
+}
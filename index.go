@@ -0,0 +1,479 @@
+package main
+
+import (
+	"encoding/json"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+)
+
+// Where fixfiles index saves the project's import graph, relative to the project root
+const indexFileName = ".fixfiles/index.json"
+
+// Version of the index format. Indexes from other versions have to be rebuilt.
+const projectIndexVersion = 2
+
+// How many files are held in memory at once while indexing
+const indexBatchSize = 1000
+
+// ProjectIndex is the import graph of every source file in a project
+type ProjectIndex struct {
+	Version int `json:"version"`
+	// Root is the project root the file paths are relative to
+	Root string `json:"root"`
+	// Files are keyed by path relative to the root, with forward slashes
+	Files map[string]*IndexedFile `json:"files"`
+}
+
+// IndexedFile is what the index knows about one file
+type IndexedFile struct {
+	Language string    `json:"language"`
+	Size     int64     `json:"size"`
+	Lines    int       `json:"lines"`
+	ModTime  time.Time `json:"modTime"`
+	Hash     string    `json:"hash"`
+	// Imports are the project files this file imports
+	Imports []string `json:"imports"`
+	// ImportedBy are the project files importing this file
+	ImportedBy []string `json:"importedBy"`
+	// External are the files outside the index this file imports, such as third-party packages
+	External []string `json:"external,omitempty"`
+	// Environment fingerprints the settings and manifests the imports were resolved with
+	Environment string `json:"environment"`
+	// Directories holds the modification times of the directories the imports were found or looked for in
+	Directories map[string]int64 `json:"directories,omitempty"`
+}
+
+// Dependent is a file importing one of a set of entry files, directly or through other files
+type Dependent struct {
+	Path string `json:"path"`
+	// Distance is how many imports away from the nearest entry file it is
+	Distance int `json:"distance"`
+}
+
+// indexPath returns where the index of a project is saved
+func indexPath(projectRoot string) string {
+	return filepath.Join(projectRoot, filepath.FromSlash(indexFileName))
+}
+
+// indexableFiles returns every source file of the project that can import other files, skipping
+// assets, data files, hidden directories and third-party code
+func indexableFiles(projectRoot string) ([]string, error) {
+	var files []string
+	err := walkDir(projectRoot, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			if path == projectRoot {
+				return err
+			}
+			return nil
+		}
+		if entry.IsDir() {
+			name := entry.Name()
+			if path != projectRoot && (strings.HasPrefix(name, ".") || name == "node_modules" || name == "vendor") {
+				return filepath.SkipDir
+			}
+			return nil
+		}
+		if isSourceExtension(fileExtension(path)) {
+			files = append(files, path)
+		}
+		return nil
+	})
+	return files, err
+}
+
+// BuildIndex parses every source file of a project and links each to the files it imports and
+// the files importing it. Files of the previous index, if there is one, are reused without being
+// parsed while they and everything their imports were resolved with are unchanged, so updating an
+// index after a few files changed only parses those. It also returns how many files were parsed.
+func BuildIndex(projectRoot string, previous *ProjectIndex) (*ProjectIndex, int, error) {
+	paths, err := indexableFiles(projectRoot)
+	if err != nil {
+		return nil, 0, err
+	}
+	indexed := make(map[string]string, len(paths))
+	for _, path := range paths {
+		indexed[path] = ""
+	}
+
+	// Manifests are fingerprinted once per directory
+	settings := resolutionSettings()
+	environments := make(map[string]string)
+	environment := func(path string) string {
+		dir := filepath.Dir(path)
+		if _, ok := environments[dir]; !ok {
+			environments[dir] = resolutionEnvironment(settings, dir, projectRoot)
+		}
+		return environments[dir]
+	}
+
+	index := &ProjectIndex{Version: projectIndexVersion, Root: projectRoot, Files: make(map[string]*IndexedFile)}
+	var jobs []fileJob
+	for _, path := range paths {
+		if file := reusableIndexEntry(previous, path, environment(path)); file != nil {
+			index.Files[bundlePath(projectRoot, path)] = file
+			continue
+		}
+		jobs = append(jobs, fileJob{path: path})
+	}
+
+	parsed := 0
+	for start := 0; start < len(jobs); start += indexBatchSize {
+		batch := jobs[start:min(start+indexBatchSize, len(jobs))]
+		hits := 0
+		if importCache != nil {
+			hits = importCache.hits
+		}
+		for _, file := range loadFiles(batch, projectRoot) {
+			if file.err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not index %s: %v\n", file.path, file.err)
+				continue
+			}
+			if file.skip {
+				continue
+			}
+			info, err := statFile(file.path)
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not index %s: %v\n", file.path, err)
+				continue
+			}
+
+			entry := &IndexedFile{
+				Language:    languageFor(fileExtension(file.path)).Name(),
+				Size:        info.Size(),
+				Lines:       strings.Count(file.content, "\n"),
+				ModTime:     info.ModTime(),
+				Hash:        contentHash([]byte(file.content)),
+				Environment: environment(file.path),
+				Directories: file.dirs,
+				// Lists are empty rather than null in the saved index
+				Imports: []string{},
+			}
+			if file.content != "" && !strings.HasSuffix(file.content, "\n") {
+				entry.Lines++
+			}
+			for _, importPath := range file.imports {
+				if imported := collectedPath(importPath, indexed); imported != "" {
+					imported = bundlePath(projectRoot, imported)
+					if imported != bundlePath(projectRoot, file.path) && !containsString(entry.Imports, imported) {
+						entry.Imports = append(entry.Imports, imported)
+					}
+				} else if info, err := statFile(importPath); err == nil && !info.IsDir() {
+					external := bundlePath(projectRoot, importPath)
+					if !containsString(entry.External, external) {
+						entry.External = append(entry.External, external)
+					}
+				}
+			}
+			sort.Strings(entry.Imports)
+			sort.Strings(entry.External)
+			index.Files[bundlePath(projectRoot, file.path)] = entry
+		}
+
+		parsed += len(batch)
+		if importCache != nil {
+			parsed -= importCache.hits - hits
+		}
+	}
+
+	// Reverse edges are worked out once every file's imports are known
+	for _, file := range index.Files {
+		file.ImportedBy = []string{}
+	}
+	for path, file := range index.Files {
+		for _, imported := range file.Imports {
+			if target, ok := index.Files[imported]; ok {
+				target.ImportedBy = append(target.ImportedBy, path)
+			}
+		}
+	}
+	for _, file := range index.Files {
+		sort.Strings(file.ImportedBy)
+	}
+
+	return index, parsed, nil
+}
+
+// reusableIndexEntry returns the previous index's entry for a file if neither the file nor
+// anything its imports were resolved with has changed since, or nil. Files with a new
+// modification time are read to compare their hash.
+func reusableIndexEntry(previous *ProjectIndex, path string, environment string) *IndexedFile {
+	if previous == nil {
+		return nil
+	}
+	old, ok := previous.Files[bundlePath(previous.Root, path)]
+	if !ok || old.Environment != environment || !directoriesUnchanged(old.Directories) {
+		return nil
+	}
+	info, err := statFile(path)
+	if err != nil || info.Size() != old.Size {
+		return nil
+	}
+	if !info.ModTime().Equal(old.ModTime) {
+		content, err := readFile(path)
+		if err != nil || contentHash(content) != old.Hash {
+			return nil
+		}
+	}
+
+	file := *old
+	file.ModTime = info.ModTime()
+	return &file
+}
+
+// Save writes the index to the project
+func (index *ProjectIndex) Save() error {
+	content, err := index.JSON()
+	if err != nil {
+		return err
+	}
+	path := indexPath(index.Root)
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so a concurrent run never reads half an index
+	temp := path + ".tmp"
+	if err := os.WriteFile(temp, []byte(content), 0644); err != nil {
+		return err
+	}
+	return os.Rename(temp, path)
+}
+
+// JSON encodes the index
+func (index *ProjectIndex) JSON() (string, error) {
+	content, err := json.MarshalIndent(index, "", "  ")
+	if err != nil {
+		return "", err
+	}
+	return string(content) + "\n", nil
+}
+
+// LoadIndex reads the index saved in a project by fixfiles index
+func LoadIndex(projectRoot string) (*ProjectIndex, error) {
+	content, err := os.ReadFile(indexPath(projectRoot))
+	if os.IsNotExist(err) {
+		return nil, fmt.Errorf("%s has no index, create one with fixfiles index", projectRoot)
+	} else if err != nil {
+		return nil, err
+	}
+
+	var index ProjectIndex
+	if err := json.Unmarshal(content, &index); err != nil {
+		return nil, fmt.Errorf("invalid index %s: %v", indexPath(projectRoot), err)
+	}
+	if index.Version != projectIndexVersion {
+		return nil, fmt.Errorf("the index of %s is from another version of fixfiles, update it with fixfiles index", projectRoot)
+	}
+	if index.Files == nil {
+		index.Files = make(map[string]*IndexedFile)
+	}
+	// The project may have moved since it was indexed
+	index.Root = projectRoot
+	return &index, nil
+}
+
+// PrintIndex builds or updates the index of a project and saves it, printing a summary or the
+// whole import graph as JSON or a Graphviz digraph
+func PrintIndex(projectRoot string, format string) error {
+	// The index directory is created first, as creating it changes the project root, which would
+	// make every file importing something next to it look changed next time
+	if err := os.MkdirAll(filepath.Dir(indexPath(projectRoot)), 0755); err != nil {
+		return fmt.Errorf("could not save index: %v", err)
+	}
+
+	// A missing or outdated index is built from scratch
+	previous, _ := LoadIndex(projectRoot)
+	index, parsed, err := BuildIndex(projectRoot, previous)
+	if err != nil {
+		return err
+	}
+	if err := index.Save(); err != nil {
+		return fmt.Errorf("could not save index: %v", err)
+	}
+
+	switch format {
+	case "json":
+		output, err := index.JSON()
+		if err != nil {
+			return err
+		}
+		fmt.Print(output)
+		return nil
+	case "dot":
+		fmt.Print(index.Dot())
+		return nil
+	}
+
+	imports := 0
+	for _, file := range index.Files {
+		imports += len(file.Imports)
+	}
+	fmt.Printf("Indexed %d files with %d imports in %s (%d parsed, %d unchanged)\n",
+		len(index.Files), imports, indexPath(projectRoot), parsed, len(index.Files)-parsed)
+	return nil
+}
+
+// Dot formats the import graph as a Graphviz digraph, with an edge from each file to each file it imports
+func (index *ProjectIndex) Dot() string {
+	var builder strings.Builder
+	fmt.Fprintf(&builder, "digraph imports {\n")
+	for _, path := range index.paths() {
+		file := index.Files[path]
+		if len(file.Imports) == 0 && len(file.ImportedBy) == 0 {
+			fmt.Fprintf(&builder, "  %q;\n", path)
+		}
+		for _, imported := range file.Imports {
+			fmt.Fprintf(&builder, "  %q -> %q;\n", path, imported)
+		}
+	}
+	fmt.Fprintf(&builder, "}\n")
+	return builder.String()
+}
+
+// paths returns the indexed paths in order
+func (index *ProjectIndex) paths() []string {
+	paths := make([]string, 0, len(index.Files))
+	for path := range index.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	return paths
+}
+
+// Dependents returns the entry files, at distance 0, and every project file that imports them,
+// directly or through other files, nearest first, as recorded in the index
+func (index *ProjectIndex) Dependents(entries []string) []Dependent {
+	var dependents []Dependent
+	seen := make(map[string]struct{})
+	for _, entry := range entries {
+		path := bundlePath(index.Root, entry)
+		if _, ok := index.Files[path]; !ok {
+			fmt.Fprintf(os.Stderr, "Warning: %s isn't in the index, update it with fixfiles index\n", entry)
+		}
+		if _, ok := seen[path]; !ok {
+			seen[path] = struct{}{}
+			dependents = append(dependents, Dependent{Path: path})
+		}
+	}
+
+	// Breadth first, so each file is found at its shortest distance
+	for i := 0; i < len(dependents); i++ {
+		file, ok := index.Files[dependents[i].Path]
+		if !ok {
+			continue
+		}
+		for _, importer := range file.ImportedBy {
+			if _, ok := seen[importer]; !ok {
+				seen[importer] = struct{}{}
+				dependents = append(dependents, Dependent{Path: importer, Distance: dependents[i].Distance + 1})
+			}
+		}
+	}
+
+	index.warnIfStale(dependents)
+	return dependents
+}
+
+// warnIfStale warns when files have changed since they were indexed
+func (index *ProjectIndex) warnIfStale(dependents []Dependent) {
+	stale := 0
+	for _, dependent := range dependents {
+		file, ok := index.Files[dependent.Path]
+		if !ok {
+			continue
+		}
+		info, err := statFile(filepath.Join(index.Root, filepath.FromSlash(dependent.Path)))
+		if err != nil || info.Size() != file.Size || !info.ModTime().Equal(file.ModTime) {
+			stale++
+		}
+	}
+	if stale > 0 {
+		fmt.Fprintf(os.Stderr, "Warning: %d files changed since the index was built, update it with fixfiles index\n", stale)
+	}
+}
+
+// FormatImpact formats the files depending on the entry files, grouped by distance
+func FormatImpact(dependents []Dependent) string {
+	var builder strings.Builder
+
+	var entries []string
+	direct := 0
+	for _, dependent := range dependents {
+		switch dependent.Distance {
+		case 0:
+			entries = append(entries, dependent.Path)
+		case 1:
+			direct++
+		}
+	}
+
+	fmt.Fprintf(&builder, "------------------------------\n")
+	fmt.Fprintf(&builder, "Files depending on %s\n", strings.Join(entries, ", "))
+	fmt.Fprintf(&builder, "------------------------------\n\n")
+	for _, dependent := range dependents {
+		if dependent.Distance > 0 {
+			fmt.Fprintf(&builder, "%3d  %s\n", dependent.Distance, dependent.Path)
+		}
+	}
+	if len(dependents) > len(entries) {
+		fmt.Fprint(&builder, "\n")
+	}
+
+	fmt.Fprintf(&builder, "------------------------------\n")
+	fmt.Fprintf(&builder, "%d dependent files, %d importing them directly\n", len(dependents)-len(entries), direct)
+	return builder.String()
+}
+
+// PrintImpact lists the files that depend on the entry files, as recorded in the index, with how
+// many imports away each is
+func PrintImpact(index *ProjectIndex, entries []string, format string) error {
+	dependents := index.Dependents(entries)
+	if format == "json" {
+		impact := struct {
+			Entries    []string    `json:"entries"`
+			Dependents []Dependent `json:"dependents"`
+		}{Entries: []string{}, Dependents: []Dependent{}}
+		for _, dependent := range dependents {
+			if dependent.Distance == 0 {
+				impact.Entries = append(impact.Entries, dependent.Path)
+			} else {
+				impact.Dependents = append(impact.Dependents, dependent)
+			}
+		}
+		content, err := json.MarshalIndent(impact, "", "  ")
+		if err != nil {
+			return err
+		}
+		fmt.Println(string(content))
+		return nil
+	}
+	fmt.Print(FormatImpact(dependents))
+	return nil
+}
+
+// IncludeDependents collects the entry files and every project file that imports them, directly
+// or through other files, as recorded in the index
+func IncludeDependents(index *ProjectIndex, entries []string, results map[string]string) {
+	for _, dependent := range index.Dependents(entries) {
+		filePath := filepath.Join(index.Root, filepath.FromSlash(dependent.Path))
+		content, err := readFile(filePath)
+		if err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not read %s: %v\n", filePath, err)
+			continue
+		}
+		results[filePath] = string(content)
+
+		if file, ok := index.Files[dependent.Path]; ok {
+			var imports []string
+			for _, imported := range file.Imports {
+				imports = append(imports, filepath.Join(index.Root, filepath.FromSlash(imported)))
+			}
+			importGraph[filePath] = imports
+		}
+	}
+}
@@ -0,0 +1,225 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"reflect"
+	"sort"
+	"testing"
+)
+
+// TestProjectIndex tests indexing a project and collecting dependents from the index
+func TestProjectIndex(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "project-index-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer func() { importCache = nil }()
+	defer resetCollection()
+
+	write := func(path string, content string) {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+	write("package.json", `{"name": "app"}`)
+	write("src/app.ts", "import { page } from './page';\nimport { format } from './utils/format';\n")
+	write("src/page.ts", "import { format } from './utils/format';\nexport const page = format('page');\n")
+	write("src/utils/format.ts", "export const format = (s: string) => s;")
+	write("src/unrelated.ts", "export const unrelated = 1;\n")
+	// Assets and data files can't import anything, so they aren't indexed
+	write("src/logo.png", "\x89PNG\r\n")
+	write("src/data.json", `{"page": 1}`)
+	write("src/widget.svelte", "<script>\n  import { page } from './page';\n</script>\n")
+	write("node_modules/lib/index.js", "module.exports = {};\n")
+	write(".cache/ignored.ts", "export const ignored = 1;\n")
+	// The index directory already exists, as it would once a project has been indexed; creating
+	// it changes the project root, which invalidates imports found next to it
+	if err := os.MkdirAll(filepath.Join(tempDir, ".fixfiles"), 0755); err != nil {
+		t.Fatalf("Failed to create index directory: %v", err)
+	}
+
+	resetCollection()
+	importCache = LoadImportCache(filepath.Join(tempDir, "cache.json"), tempDir)
+	index, parsed, err := BuildIndex(tempDir, nil)
+	if err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	if parsed != 5 {
+		t.Errorf("Expected 5 files to be parsed, got %d", parsed)
+	}
+
+	var paths []string
+	for path := range index.Files {
+		paths = append(paths, path)
+	}
+	sort.Strings(paths)
+	expectedPaths := []string{"src/app.ts", "src/page.ts", "src/unrelated.ts", "src/utils/format.ts", "src/widget.svelte"}
+	if !reflect.DeepEqual(paths, expectedPaths) {
+		t.Fatalf("Expected indexed files %v, got %v", expectedPaths, paths)
+	}
+
+	format := index.Files["src/utils/format.ts"]
+	if format.Language != "JavaScript" || format.Lines != 1 || format.Size == 0 || format.Hash == "" {
+		t.Errorf("Unexpected metadata for format.ts: %+v", format)
+	}
+	if expected := []string{"src/app.ts", "src/page.ts"}; !reflect.DeepEqual(format.ImportedBy, expected) {
+		t.Errorf("Expected format.ts to be imported by %v, got %v", expected, format.ImportedBy)
+	}
+	if expected := []string{"src/page.ts", "src/utils/format.ts"}; !reflect.DeepEqual(index.Files["src/app.ts"].Imports, expected) {
+		t.Errorf("Expected app.ts to import %v, got %v", expected, index.Files["src/app.ts"].Imports)
+	}
+
+	// The index is saved in the project and loaded back
+	if err := index.Save(); err != nil {
+		t.Fatalf("Save failed: %v", err)
+	}
+	loaded, err := LoadIndex(tempDir)
+	if err != nil {
+		t.Fatalf("LoadIndex failed: %v", err)
+	}
+	page, loadedPage := index.Files["src/page.ts"], loaded.Files["src/page.ts"]
+	if loadedPage == nil || loadedPage.Hash != page.Hash || !loadedPage.ModTime.Equal(page.ModTime) ||
+		!reflect.DeepEqual(loadedPage.Imports, page.Imports) || !reflect.DeepEqual(loadedPage.ImportedBy, page.ImportedBy) {
+		t.Errorf("Expected the loaded index to match %+v, got %+v", page, loadedPage)
+	}
+
+	// Dependents are collected transitively
+	results := make(map[string]string)
+	IncludeDependents(loaded, []string{filepath.Join(tempDir, "src/utils/format.ts")}, results)
+	var collected []string
+	for path := range results {
+		collected = append(collected, bundlePath(tempDir, path))
+	}
+	sort.Strings(collected)
+	if expected := []string{"src/app.ts", "src/page.ts", "src/utils/format.ts", "src/widget.svelte"}; !reflect.DeepEqual(collected, expected) {
+		t.Errorf("Expected dependents %v, got %v", expected, collected)
+	}
+
+	// Updating the index only parses the files that changed, from the saved index alone
+	importCache = nil
+	write("src/unrelated.ts", "import { page } from './page';\nimport { missing } from './missing';\n")
+	resetCollection()
+	index, parsed, err = BuildIndex(tempDir, loaded)
+	if err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	if parsed != 1 {
+		t.Errorf("Expected only the changed file to be parsed, got %d", parsed)
+	}
+	if expected := []string{"src/app.ts", "src/unrelated.ts", "src/widget.svelte"}; !reflect.DeepEqual(index.Files["src/page.ts"].ImportedBy, expected) {
+		t.Errorf("Expected page.ts to be imported by %v, got %v", expected, index.Files["src/page.ts"].ImportedBy)
+	}
+
+	// Creating a file an import wasn't found at parses the importing file again
+	write("src/missing.ts", "export const missing = 1;\n")
+	resetCollection()
+	index, _, err = BuildIndex(tempDir, index)
+	if err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	if expected := []string{"src/missing.ts", "src/page.ts"}; !reflect.DeepEqual(index.Files["src/unrelated.ts"].Imports, expected) {
+		t.Errorf("Expected unrelated.ts to import %v, got %v", expected, index.Files["src/unrelated.ts"].Imports)
+	}
+}
+
+// TestIndexDependents tests finding the dependents of files at their distance, and exporting the graph
+func TestIndexDependents(t *testing.T) {
+	index := &ProjectIndex{Root: "/project", Files: map[string]*IndexedFile{
+		"a.ts":     {Imports: []string{"b.ts", "c.ts"}, ImportedBy: []string{"d.ts"}},
+		"b.ts":     {Imports: []string{"c.ts"}, ImportedBy: []string{"a.ts"}},
+		"c.ts":     {Imports: []string{}, ImportedBy: []string{"a.ts", "b.ts"}},
+		"d.ts":     {Imports: []string{"a.ts"}, ImportedBy: []string{}},
+		"alone.ts": {Imports: []string{}, ImportedBy: []string{}},
+	}}
+
+	tests := []struct {
+		entries  []string
+		expected []Dependent
+	}{
+		{[]string{"/project/c.ts"}, []Dependent{{"c.ts", 0}, {"a.ts", 1}, {"b.ts", 1}, {"d.ts", 2}}},
+		{[]string{"/project/b.ts", "/project/a.ts"}, []Dependent{{"b.ts", 0}, {"a.ts", 0}, {"d.ts", 1}}},
+		{[]string{"/project/alone.ts"}, []Dependent{{"alone.ts", 0}}},
+	}
+	for _, test := range tests {
+		if dependents := index.Dependents(test.entries); !reflect.DeepEqual(dependents, test.expected) {
+			t.Errorf("Dependents(%v): expected %v, got %v", test.entries, test.expected, dependents)
+		}
+	}
+
+	expected := `digraph imports {
+  "a.ts" -> "b.ts";
+  "a.ts" -> "c.ts";
+  "alone.ts";
+  "b.ts" -> "c.ts";
+  "d.ts" -> "a.ts";
+}
+`
+	if dot := index.Dot(); dot != expected {
+		t.Errorf("Expected graph:\n%s\ngot:\n%s", expected, dot)
+	}
+}
+
+// TestLoadIndexMissing tests that a project without an index is reported
+func TestLoadIndexMissing(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "project-index-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if _, err := LoadIndex(tempDir); err == nil {
+		t.Errorf("Expected an error for a project without an index")
+	}
+}
+
+// TestIndexReuseNewFile tests that reusing the previous index parses a file again when a file it
+// looked for an import at is created, in a language that searches several directories
+func TestIndexReuseNewFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "project-index-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer resetCollection()
+
+	write := func(path string, content string) {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+	write("Gemfile", "")
+	write("app/main.rb", "require 'helper'\n")
+
+	resetCollection()
+	index, _, err := BuildIndex(tempDir, nil)
+	if err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	if imports := index.Files["app/main.rb"].Imports; len(imports) != 0 {
+		t.Fatalf("Expected main.rb to import nothing yet, got %v", imports)
+	}
+
+	write("lib/helper.rb", "module Helper; end\n")
+	resetCollection()
+	index, parsed, err := BuildIndex(tempDir, index)
+	if err != nil {
+		t.Fatalf("BuildIndex failed: %v", err)
+	}
+	if parsed != 2 {
+		t.Errorf("Expected main.rb and the new file to be parsed, got %d", parsed)
+	}
+	if expected := []string{"lib/helper.rb"}; !reflect.DeepEqual(index.Files["app/main.rb"].Imports, expected) {
+		t.Errorf("Expected main.rb to import %v, got %v", expected, index.Files["app/main.rb"].Imports)
+	}
+}
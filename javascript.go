@@ -0,0 +1,547 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Extensions of JavaScript and TypeScript files, whose imports are found by lexing rather than patterns
+var javaScriptExtensions = []string{".js", ".jsx", ".mjs", ".cjs", ".ts", ".tsx", ".mts", ".cts", ".d.ts", ".d.mts", ".d.cts"}
+
+// Extensions in which a < can start a JSX element. Plain TypeScript uses it for type assertions.
+var jsxExtensions = []string{".js", ".jsx", ".tsx"}
+
+// Matches the path and types attributes of a /// <reference /> directive
+var tripleSlashPattern = regexp.MustCompile(`^///\s*<reference\s+(path|types)\s*=\s*["']([^"']+)["']`)
+
+// Tags of template literals containing GraphQL documents
+var graphqlTags = []string{"gql", "graphql"}
+
+// Keywords after which a / starts a regular expression rather than a division
+var regexPrefixKeywords = []string{
+	"return", "typeof", "instanceof", "in", "of", "new", "delete", "void",
+	"throw", "case", "do", "else", "yield", "await", "extends",
+}
+
+// javaScriptLanguage handles JavaScript and TypeScript, resolving bare specifiers like Node and
+// adding type declarations for TypeScript files
+type javaScriptLanguage struct{}
+
+func init() {
+	RegisterLanguage(javaScriptLanguage{})
+}
+
+// Name identifies the language
+func (javaScriptLanguage) Name() string {
+	return "JavaScript"
+}
+
+// Detect reports whether a file is JavaScript or TypeScript
+func (javaScriptLanguage) Detect(fileExt string) bool {
+	return containsString(javaScriptExtensions, fileExt)
+}
+
+// ExtractSpecifiers lexes a file for the modules it imports, and the GraphQL fragments its gql
+// templates spread without defining
+func (javaScriptLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	lexer := lexJS(file.Content, file.Ext)
+
+	var specs []Specifier
+	for _, path := range lexer.specifiers() {
+		specs = append(specs, Specifier{Path: path})
+	}
+	for _, name := range graphqlMissingFragments(lexer.graphqlDocuments) {
+		specs = append(specs, Specifier{Path: name, Kind: "fragment"})
+	}
+	return specs
+}
+
+// IsBuiltin reports whether a specifier is a Node built-in module
+func (javaScriptLanguage) IsBuiltin(spec Specifier) bool {
+	return spec.Kind == "" && (strings.HasPrefix(spec.Path, "node:") || containsString(nodeBuiltinModules, strings.SplitN(spec.Path, "/", 2)[0]))
+}
+
+// ResolveSpecifier resolves package specifiers through Node resolution and paths and aliases
+// through the project, along with any declarations for TypeScript files
+func (javaScriptLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	if spec.Kind == "fragment" {
+		return []string{graphqlFragmentFile(spec.Path, file.ProjectRoot)}
+	}
+
+	var imports []string
+	importPath := spec.Path
+	isTypeScript := containsString(typeScriptExtensions, file.Ext)
+
+	// Bare and #subpath specifiers go through Node package resolution first
+	if isBareSpecifier(importPath) {
+		if resolved, handled := resolveNodeSpecifier(importPath, file.Path, file.ProjectRoot); handled {
+			if resolved != "" {
+				imports = append(imports, resolved)
+			}
+			if isTypeScript {
+				imports = append(imports, typeDeclarationsFor(importPath, resolved, file.Path, file.ProjectRoot)...)
+			}
+			return imports
+		}
+		if isTypeScript {
+			imports = append(imports, typeDeclarationsFor(importPath, "", file.Path, file.ProjectRoot)...)
+		}
+	}
+
+	// Try to resolve the import path to an actual file
+	resolvedPath, err := ResolveImportPath(importPath, filepath.Dir(file.Path), file.ProjectRoot)
+	if err != nil {
+		fmt.Fprintf(os.Stderr, "Warning: could not resolve import path %s: %v\n", importPath, err)
+		return imports
+	}
+
+	// Check if this is a relative path that might be part of the project
+	if strings.HasPrefix(importPath, ".") ||
+		strings.HasPrefix(importPath, "/") ||
+		strings.HasPrefix(importPath, "@") ||
+		strings.HasPrefix(importPath, "~") ||
+		// For local imports without special prefixes
+		(!strings.Contains(importPath, "/") && !isBuiltinModule(importPath, file.Ext)) {
+		if isTypeScript {
+			resolvedPath = resolveTypeScriptSource(resolvedPath)
+			imports = append(imports, typeDeclarationsFor(importPath, resolvedPath, file.Path, file.ProjectRoot)...)
+		}
+		imports = append(imports, resolvedPath)
+	}
+	// Skip external imports (node_modules, npm packages, etc.)
+
+	return imports
+}
+
+// jsTokenKind classifies the tokens produced by jsLexer
+type jsTokenKind int
+
+const (
+	jsIdent jsTokenKind = iota
+	jsString
+	jsTemplate
+	jsNumber
+	jsRegex
+	jsPunct
+	jsJSX
+)
+
+// jsToken is a lexed token. Strings and templates without substitutions carry their value.
+type jsToken struct {
+	kind  jsTokenKind
+	value string
+}
+
+// jsLexer splits JavaScript and TypeScript source into tokens, skipping comments, string
+// contents, regular expressions and JSX text so that only real code is searched for imports
+type jsLexer struct {
+	src    []byte
+	pos    int
+	jsx    bool
+	tokens []jsToken
+	// Files referenced by /// <reference /> directives at the top of the file
+	references []string
+	// Contents of gql and graphql tagged templates
+	graphqlDocuments []string
+}
+
+// extractJSSpecifiers returns the module specifiers a JavaScript or TypeScript file imports, in
+// source order: static imports and re-exports, import x = require(), dynamic import() and
+// require()/require.resolve() with literal arguments, and /// <reference /> directives
+func extractJSSpecifiers(content []byte, fileExt string) []string {
+	return lexJS(content, fileExt).specifiers()
+}
+
+// lexJS tokenizes JavaScript or TypeScript source
+func lexJS(content []byte, fileExt string) *jsLexer {
+	lexer := &jsLexer{src: content, jsx: containsString(jsxExtensions, fileExt)}
+
+	// A hashbang line is only allowed at the very start
+	if strings.HasPrefix(string(content), "#!") {
+		for lexer.pos < len(content) && content[lexer.pos] != '\n' {
+			lexer.pos++
+		}
+	}
+	lexer.scan(false)
+
+	return lexer
+}
+
+// specifiers finds the module specifiers in the lexed tokens
+func (l *jsLexer) specifiers() []string {
+	specifiers := append([]string{}, l.references...)
+	tokens := l.tokens
+	at := func(i int) jsToken {
+		if i < 0 || i >= len(tokens) {
+			return jsToken{kind: jsPunct}
+		}
+		return tokens[i]
+	}
+	isPunct := func(i int, value string) bool {
+		return at(i).kind == jsPunct && at(i).value == value
+	}
+	isIdent := func(i int, value string) bool {
+		return at(i).kind == jsIdent && at(i).value == value
+	}
+	// Adds the string token at i, once, since import x = require() matches two rules
+	added := make(map[int]bool)
+	add := func(i int) {
+		if !added[i] {
+			added[i] = true
+			specifiers = append(specifiers, tokens[i].value)
+		}
+	}
+
+	for i, token := range tokens {
+		// Member accesses like foo.import or module.require aren't module references
+		if token.kind != jsIdent || isPunct(i-1, ".") {
+			continue
+		}
+
+		switch token.value {
+		case "import":
+			switch {
+			case at(i+1).kind == jsString:
+				// import "./polyfills"
+				add(i + 1)
+			case isPunct(i+1, "("):
+				// import("./page"), optionally with import attributes
+				if at(i+2).kind == jsString && (isPunct(i+3, ")") || isPunct(i+3, ",")) {
+					add(i + 2)
+				}
+			case isPunct(i+1, "."):
+				// import.meta
+			default:
+				for j := i + 1; j < len(tokens); j++ {
+					if isIdent(j, "from") && at(j+1).kind == jsString {
+						add(j + 1)
+						break
+					}
+					// TypeScript's import x = require("./x")
+					if isPunct(j, "=") {
+						if isIdent(j+1, "require") && isPunct(j+2, "(") && at(j+3).kind == jsString {
+							add(j + 3)
+						}
+						break
+					}
+					if isPunct(j, ";") || isPunct(j, "(") || isPunct(j, ")") {
+						break
+					}
+				}
+			}
+
+		case "export":
+			j := i + 1
+			if isIdent(j, "type") {
+				j++
+			}
+			switch {
+			case isPunct(j, "*"):
+				// export * from "./a", export * as ns from "./a"
+				for ; j < len(tokens) && j <= i+5; j++ {
+					if isIdent(j, "from") && at(j+1).kind == jsString {
+						add(j + 1)
+						break
+					}
+				}
+			case isPunct(j, "{"):
+				// export { a, b as c } from "./a"
+				for j < len(tokens) && !isPunct(j, "}") {
+					j++
+				}
+				if isIdent(j+1, "from") && at(j+2).kind == jsString {
+					add(j + 2)
+				}
+			}
+
+		case "require":
+			if isPunct(i+1, "(") && at(i+2).kind == jsString && isPunct(i+3, ")") {
+				add(i + 2)
+			} else if isPunct(i+1, ".") && isIdent(i+2, "resolve") && isPunct(i+3, "(") && at(i+4).kind == jsString {
+				add(i + 4)
+			}
+		}
+	}
+
+	return specifiers
+}
+
+// scan lexes tokens until the end of the source or, when nested, until the } closing a template
+// substitution or JSX expression
+func (l *jsLexer) scan(nested bool) {
+	depth := 0
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == ' ' || c == '\t' || c == '\n' || c == '\r':
+			l.pos++
+		case c == '/' && l.peek(1) == '/':
+			l.lineComment()
+		case c == '/' && l.peek(1) == '*':
+			end := strings.Index(string(l.src[l.pos+2:]), "*/")
+			if end < 0 {
+				l.pos = len(l.src)
+			} else {
+				l.pos += 2 + end + 2
+			}
+		case c == '\'' || c == '"':
+			l.emit(jsString, l.quoted(c))
+		case c == '`':
+			l.template()
+		case c == '/' && l.regexAllowed():
+			start := l.pos
+			l.regex()
+			l.emit(jsRegex, string(l.src[start:l.pos]))
+		case c == '<' && l.jsx && l.regexAllowed() && l.startsJSX():
+			l.jsxElement()
+			l.emit(jsJSX, "")
+		case isJSIdentByte(c) && !(c >= '0' && c <= '9'):
+			start := l.pos
+			for l.pos < len(l.src) && isJSIdentByte(l.src[l.pos]) {
+				l.pos++
+			}
+			l.emit(jsIdent, string(l.src[start:l.pos]))
+		case c >= '0' && c <= '9' || c == '.' && l.peek(1) >= '0' && l.peek(1) <= '9':
+			start := l.pos
+			for l.pos < len(l.src) && (isJSIdentByte(l.src[l.pos]) || l.src[l.pos] == '.') {
+				l.pos++
+			}
+			l.emit(jsNumber, string(l.src[start:l.pos]))
+		default:
+			l.pos++
+			if c == '{' {
+				depth++
+			} else if c == '}' {
+				if nested && depth == 0 {
+					return
+				}
+				depth--
+			}
+			l.emit(jsPunct, string(c))
+		}
+	}
+}
+
+// emit appends a token
+func (l *jsLexer) emit(kind jsTokenKind, value string) {
+	l.tokens = append(l.tokens, jsToken{kind: kind, value: value})
+}
+
+// peek returns the byte offset bytes ahead, or 0 past the end
+func (l *jsLexer) peek(offset int) byte {
+	if l.pos+offset < len(l.src) {
+		return l.src[l.pos+offset]
+	}
+	return 0
+}
+
+// lineComment skips a // comment, recording /// <reference /> directives that precede any code
+func (l *jsLexer) lineComment() {
+	start := l.pos
+	for l.pos < len(l.src) && l.src[l.pos] != '\n' {
+		l.pos++
+	}
+	if len(l.tokens) > 0 {
+		return
+	}
+
+	match := tripleSlashPattern.FindSubmatch(l.src[start:l.pos])
+	if match == nil {
+		return
+	}
+	reference := string(match[2])
+	// Reference paths are always relative to the file, type references name packages
+	if string(match[1]) == "path" && !strings.HasPrefix(reference, ".") && !strings.HasPrefix(reference, "/") {
+		reference = "./" + reference
+	}
+	l.references = append(l.references, reference)
+}
+
+// quoted lexes a string literal and returns its value
+func (l *jsLexer) quoted(quote byte) string {
+	var value strings.Builder
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		l.pos++
+		switch {
+		case c == quote:
+			return value.String()
+		case c == '\\' && l.pos < len(l.src):
+			value.WriteByte(l.src[l.pos])
+			l.pos++
+		case c == '\n':
+			// Unterminated string
+			return value.String()
+		default:
+			value.WriteByte(c)
+		}
+	}
+	return value.String()
+}
+
+// template lexes a template literal. Substitutions are lexed as code, and templates without
+// them are emitted as strings so import(`./page`) is recognized.
+func (l *jsLexer) template() {
+	var value strings.Builder
+	substituted := false
+	graphql := len(l.tokens) > 0 && l.tokens[len(l.tokens)-1].kind == jsIdent &&
+		containsString(graphqlTags, l.tokens[len(l.tokens)-1].value)
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		switch {
+		case c == '`':
+			l.pos++
+			if graphql {
+				l.graphqlDocuments = append(l.graphqlDocuments, value.String())
+			}
+			if substituted {
+				l.emit(jsTemplate, "")
+			} else {
+				l.emit(jsString, value.String())
+			}
+			return
+		case c == '\\' && l.pos+1 < len(l.src):
+			value.WriteByte(l.src[l.pos+1])
+			l.pos += 2
+		case c == '$' && l.peek(1) == '{':
+			substituted = true
+			value.WriteByte(' ')
+			l.pos += 2
+			l.scan(true)
+		default:
+			value.WriteByte(c)
+			l.pos++
+		}
+	}
+}
+
+// regex skips a regular expression literal and its flags
+func (l *jsLexer) regex() {
+	inClass := false
+	l.pos++
+	for l.pos < len(l.src) {
+		c := l.src[l.pos]
+		l.pos++
+		switch {
+		case c == '\\':
+			l.pos++
+		case c == '\n':
+			return
+		case c == '[':
+			inClass = true
+		case c == ']':
+			inClass = false
+		case c == '/' && !inClass:
+			for l.pos < len(l.src) && isJSIdentByte(l.src[l.pos]) {
+				l.pos++
+			}
+			return
+		}
+	}
+}
+
+// regexAllowed reports whether an expression can start at the current position, based on the
+// previous token: after an operand a / divides and a < compares
+func (l *jsLexer) regexAllowed() bool {
+	if len(l.tokens) == 0 {
+		return true
+	}
+	prev := l.tokens[len(l.tokens)-1]
+	switch prev.kind {
+	case jsIdent:
+		return containsString(regexPrefixKeywords, prev.value)
+	case jsPunct:
+		return prev.value != ")" && prev.value != "]"
+	}
+	return false
+}
+
+// startsJSX reports whether the < at the current position opens a JSX element rather than a
+// TypeScript generic arrow function like <T,>(x: T) => x
+func (l *jsLexer) startsJSX() bool {
+	next := l.peek(1)
+	if next == '>' {
+		return true
+	}
+	if !isJSIdentByte(next) || next >= '0' && next <= '9' {
+		return false
+	}
+
+	rest := l.src[l.pos+1:]
+	end := 0
+	for end < len(rest) && (isJSIdentByte(rest[end]) || rest[end] == '.' || rest[end] == ':' || rest[end] == '-') {
+		end++
+	}
+	after := strings.TrimLeft(string(rest[end:]), " \t\r\n")
+	return !strings.HasPrefix(after, ",") && !strings.HasPrefix(after, "extends ")
+}
+
+// jsxElement skips a JSX element or fragment, lexing the expressions in its attributes and children
+func (l *jsLexer) jsxElement() {
+	if l.jsxTag() {
+		return
+	}
+
+	// Children are text, expressions and nested elements up to the closing tag
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '{':
+			l.pos++
+			l.scan(true)
+		case c == '<' && l.peek(1) == '/':
+			for l.pos < len(l.src) && l.src[l.pos] != '>' {
+				l.pos++
+			}
+			l.pos++
+			return
+		case c == '<':
+			l.jsxElement()
+		default:
+			l.pos++
+		}
+	}
+}
+
+// jsxTag lexes an opening tag and reports whether it was self-closing
+func (l *jsLexer) jsxTag() bool {
+	l.pos++
+	for l.pos < len(l.src) {
+		switch c := l.src[l.pos]; {
+		case c == '>':
+			l.pos++
+			return false
+		case c == '/' && l.peek(1) == '>':
+			l.pos += 2
+			return true
+		case c == '"' || c == '\'':
+			// Attribute strings have no escapes
+			end := strings.IndexByte(string(l.src[l.pos+1:]), c)
+			if end < 0 {
+				l.pos = len(l.src)
+			} else {
+				l.pos += end + 2
+			}
+		case c == '{':
+			l.pos++
+			l.scan(true)
+		case c == '<':
+			// An element as an attribute value
+			l.jsxElement()
+		default:
+			l.pos++
+		}
+	}
+	return true
+}
+
+// isJSIdentByte reports whether c can be part of an identifier. Non-ASCII bytes are treated as
+// identifier characters, as are # for private names.
+func isJSIdentByte(c byte) bool {
+	return c >= 'a' && c <= 'z' || c >= 'A' && c <= 'Z' || c >= '0' && c <= '9' ||
+		c == '_' || c == '$' || c == '#' || c >= 0x80
+}
@@ -0,0 +1,137 @@
+package main
+
+import (
+	"reflect"
+	"testing"
+)
+
+// TestExtractJSSpecifiers tests lexing JavaScript and TypeScript for the modules they import
+func TestExtractJSSpecifiers(t *testing.T) {
+	testCases := []struct {
+		name     string
+		ext      string
+		content  string
+		expected []string
+	}{
+		{
+			name: "Static imports",
+			ext:  ".js",
+			content: `import React from 'react';
+import {
+  useState,
+  useEffect,
+} from "./hooks";
+import * as api from './api'
+import './polyfills';
+import type { User } from './types';
+import def, { from } from './from';`,
+			expected: []string{"react", "./hooks", "./api", "./polyfills", "./types", "./from"},
+		},
+		{
+			name: "Re-exports",
+			ext:  ".ts",
+			content: `export * from './a';
+export * as b from './b';
+export { c, d as e } from './c';
+export type { F } from './f';
+export const g = 1;
+export { g };
+export default function h() {}`,
+			expected: []string{"./a", "./b", "./c", "./f"},
+		},
+		{
+			name: "Require and dynamic imports",
+			ext:  ".cjs",
+			content: "const a = require('./a');\n" +
+				"const b = require.resolve(\"./b\");\n" +
+				"const c = await import('./c', { with: { type: 'json' } });\n" +
+				"const d = import(`./d`);\n" +
+				"const e = import(`./pages/${name}`);\n" +
+				"const f = require(name);\n" +
+				"module.require('./g');\n" +
+				"console.log(import.meta.url);",
+			expected: []string{"./a", "./b", "./c", "./d"},
+		},
+		{
+			name: "TypeScript import require",
+			ext:  ".ts",
+			content: `import fs = require("fs");
+import type Config = require('./config');
+import Alias = Namespace.Inner;`,
+			expected: []string{"fs", "./config"},
+		},
+		{
+			name: "Comments and strings are ignored",
+			ext:  ".js",
+			content: `// import a from './a';
+/* import b from './b';
+   require('./c'); */
+const s = "import d from './d'";
+const t = ` + "`require('./e') ${value}`" + `;
+const re = /import f from '.\/f'/g;
+const ratio = total / count / 2; import g from './g';`,
+			expected: []string{"./g"},
+		},
+		{
+			name: "JSX text and attributes",
+			ext:  ".jsx",
+			content: `import Button from './Button';
+export default () => (
+  <div title="Don't import './x'">
+    Can't require('./y') here
+    {show && <Lazy load={() => import('./Lazy')} />}
+    <>{/* import './z' */}</>
+  </div>
+);
+const after = require('./after');`,
+			expected: []string{"./Button", "./Lazy", "./after"},
+		},
+		{
+			name:     "Generic arrow functions in TSX",
+			ext:      ".tsx",
+			content:  "const id = <T,>(x: T) => x;\nconst first = <T extends unknown[]>(xs: T) => xs[0];\nimport a from './a';",
+			expected: []string{"./a"},
+		},
+		{
+			name: "Triple-slash references",
+			ext:  ".d.ts",
+			content: `/// <reference path="globals.d.ts" />
+/// <reference types="node" />
+declare module 'x' {}
+/// <reference path="ignored.d.ts" />`,
+			expected: []string{"./globals.d.ts", "node"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			specifiers := extractJSSpecifiers([]byte(tc.content), tc.ext)
+			if !reflect.DeepEqual(specifiers, tc.expected) {
+				t.Errorf("Expected %v, got %v", tc.expected, specifiers)
+			}
+		})
+	}
+}
+
+// TestJSIsBuiltin tests that only module specifiers can name Node built-ins
+func TestJSIsBuiltin(t *testing.T) {
+	testCases := []struct {
+		spec     Specifier
+		expected bool
+	}{
+		{Specifier{Path: "fs"}, true},
+		{Specifier{Path: "fs/promises"}, true},
+		{Specifier{Path: "node:test"}, true},
+		{Specifier{Path: "./fs"}, false},
+		{Specifier{Path: "lodash"}, false},
+		// GraphQL fragments can share a name with a built-in module
+		{Specifier{Path: "events", Kind: "fragment"}, false},
+		{Specifier{Path: "node:fs", Kind: "fragment"}, false},
+	}
+
+	for _, tc := range testCases {
+		if builtin := (javaScriptLanguage{}).IsBuiltin(tc.spec); builtin != tc.expected {
+			t.Errorf("IsBuiltin(%+v) = %v, expected %v", tc.spec, builtin, tc.expected)
+		}
+	}
+}
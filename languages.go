@@ -0,0 +1,110 @@
+package main
+
+// SourceFile is a file, or a block embedded in one, whose imports are being extracted
+type SourceFile struct {
+	// Path of the file on disk. Embedded blocks share the path of the file containing them.
+	Path string
+	// Ext is the extension of the language Content is written in, e.g. .scss for a Vue <style lang="scss">
+	Ext         string
+	Content     []byte
+	ProjectRoot string
+	// searched collects the paths resolvers looked for references at, shared with embedded blocks
+	searched *[]string
+}
+
+// Searched records that a resolver looked for a reference at each path, whether or not something
+// was found there, so the file's imports are resolved again when a file is created or removed
+// next to one of them. Paths may be given without the extensions the resolver tries.
+func (file *SourceFile) Searched(paths ...string) {
+	if file.searched != nil {
+		*file.searched = append(*file.searched, paths...)
+	}
+}
+
+// Specifier is a reference to another module or file as written in the source
+type Specifier struct {
+	// Path is the module, file or namespace, e.g. ./utils, github.com/pkg/errors or App.Models
+	Path string
+	// Kind is the language specific form of the reference, e.g. require_relative or @use, for
+	// languages that resolve different statements differently
+	Kind string
+	// Block is set instead of Path for code embedded in the file, such as an inline <script> in a
+	// page or the <style> of a component. It's extracted by the language registered for its extension.
+	Block *SourceFile
+}
+
+// Language extracts and resolves the imports of one kind of source file. Languages are added
+// with RegisterLanguage, typically from an init function in the file implementing them.
+type Language interface {
+	// Name identifies the language
+	Name() string
+	// Detect reports whether the language handles files with the given extension
+	Detect(fileExt string) bool
+	// ExtractSpecifiers returns the references a file makes, in source order
+	ExtractSpecifiers(file *SourceFile) []Specifier
+	// ResolveSpecifier returns the files a reference refers to, or nothing for references that
+	// can't be found or shouldn't be followed
+	ResolveSpecifier(file *SourceFile, spec Specifier) []string
+	// IsBuiltin reports whether a reference names a standard library module, which is never resolved
+	IsBuiltin(spec Specifier) bool
+}
+
+// Registered languages, in registration order
+var languages []Language
+
+// RegisterLanguage adds a language. A language registered later takes precedence over earlier
+// ones for the extensions it detects, so custom languages can replace the built-in ones. fixfiles
+// is a main package, so languages are registered by files compiled into it, not by other modules.
+func RegisterLanguage(lang Language) {
+	languages = append(languages, lang)
+}
+
+// languageFor returns the language handling files with the given extension, or nil
+func languageFor(fileExt string) Language {
+	for i := len(languages) - 1; i >= 0; i-- {
+		if languages[i].Detect(fileExt) {
+			return languages[i]
+		}
+	}
+	return nil
+}
+
+// extractSourceImports resolves every reference of a file, and of the blocks embedded in it, to
+// the files it imports
+func extractSourceImports(file *SourceFile) []string {
+	lang := languageFor(file.Ext)
+	if lang == nil {
+		return nil
+	}
+
+	var imports []string
+	add := func(path string) {
+		if path != "" && path != file.Path && !containsString(imports, path) {
+			imports = append(imports, path)
+		}
+	}
+
+	for _, spec := range lang.ExtractSpecifiers(file) {
+		var paths []string
+		switch {
+		case spec.Block != nil:
+			spec.Block.searched = file.searched
+			paths = extractSourceImports(spec.Block)
+		case !lang.IsBuiltin(spec):
+			paths = lang.ResolveSpecifier(file, spec)
+		}
+		for _, path := range paths {
+			add(path)
+		}
+	}
+
+	return imports
+}
+
+// resolveSourceImports resolves the imports of a file like extractSourceImports, and also returns
+// the paths its resolvers searched
+func resolveSourceImports(file *SourceFile) ([]string, []string) {
+	var searched []string
+	file.searched = &searched
+	return extractSourceImports(file), searched
+}
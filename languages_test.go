@@ -0,0 +1,121 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+	"testing"
+)
+
+// Matches the include lines of the test DSL
+var recipeIncludePattern = regexp.MustCompile(`(?m)^include\s+(\S+)`)
+
+// recipeLanguage is a custom language for a made-up DSL, registered the way a team would add their own
+type recipeLanguage struct{}
+
+func (recipeLanguage) Name() string { return "recipe" }
+
+func (recipeLanguage) Detect(fileExt string) bool { return fileExt == ".recipe" }
+
+func (recipeLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+	for _, match := range recipeIncludePattern.FindAllSubmatch(file.Content, -1) {
+		specs = append(specs, Specifier{Path: string(match[1])})
+	}
+	return specs
+}
+
+func (recipeLanguage) IsBuiltin(spec Specifier) bool { return strings.HasPrefix(spec.Path, "std/") }
+
+func (recipeLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	return []string{filepath.Join(file.ProjectRoot, "recipes", spec.Path+".recipe")}
+}
+
+// TestRegisterLanguage tests extracting imports with a registered custom language
+func TestRegisterLanguage(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "register-language-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	registered := languages
+	defer func() { languages = registered }()
+	RegisterLanguage(recipeLanguage{})
+
+	if lang := languageFor(".recipe"); lang == nil || lang.Name() != "recipe" {
+		t.Fatalf("Expected the recipe language for .recipe files, got %v", lang)
+	}
+	if lang := languageFor(".go"); lang == nil || lang.Name() != "Go" {
+		t.Fatalf("Expected the built-in Go language for .go files, got %v", lang)
+	}
+
+	mainPath := filepath.Join(tempDir, "main.recipe")
+	content := []byte("include bread\ninclude std/salt\ninclude bread\n")
+	imports := extractImportsFromContent(mainPath, ".recipe", content, tempDir)
+
+	// Built-ins are skipped and duplicates collapsed
+	expected := filepath.Join(tempDir, "recipes", "bread.recipe")
+	if len(imports) != 1 || imports[0] != expected {
+		t.Errorf("Expected [%s], got %v", expected, imports)
+	}
+
+	// A later registration takes precedence for the extensions it detects
+	RegisterLanguage(overrideLanguage{recipeLanguage{}})
+	if lang := languageFor(".recipe"); lang == nil || lang.Name() != "override" {
+		t.Errorf("Expected the later registration to take precedence, got %v", lang)
+	}
+}
+
+// overrideLanguage wraps another language under a different name
+type overrideLanguage struct {
+	Language
+}
+
+func (overrideLanguage) Name() string { return "override" }
+
+// TestRegisterLanguageProcessFile tests that files of a registered language are collected end to end
+func TestRegisterLanguageProcessFile(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "register-language-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer resetCollection()
+
+	registered := languages
+	defer func() { languages = registered }()
+	RegisterLanguage(recipeLanguage{})
+
+	files := map[string]string{
+		"Makefile":             "",
+		"main.recipe":          "include bread\ninclude std/salt\n",
+		"recipes/bread.recipe": "include flour\n",
+		"recipes/flour.recipe": "",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	resetCollection()
+	results := make(map[string]string)
+	if err := ProcessFile(filepath.Join(tempDir, "main.recipe"), tempDir, results); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	for _, path := range []string{"main.recipe", "recipes/bread.recipe", "recipes/flour.recipe"} {
+		if _, ok := results[filepath.Join(tempDir, path)]; !ok {
+			t.Errorf("Expected %s to be collected, got %v", path, resultPaths(results))
+		}
+	}
+	if len(results) != 3 {
+		t.Errorf("Expected 3 files, got %v", resultPaths(results))
+	}
+}
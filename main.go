@@ -5,6 +5,7 @@ import (
 	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 )
 
 // formatPath ensures consistent path formatting
@@ -14,39 +15,417 @@ func formatPath(path string) string {
 
 func main() {
 	// Parse command line arguments
-	flag.Parse()
+	rails := flag.Bool("rails", false, "Resolve Ruby constant references using Rails autoload conventions")
+	pairSources := flag.Bool("pair-sources", false, "Include the matching .c/.cpp file for each included C/C++ header")
+	includeAssets := flag.Bool("include-assets", false, "Include images, fonts and media referenced from HTML")
+	includeExternal := flag.String("include-external", "", "Comma separated third-party packages whose sources should be followed")
+	includeExternalDepth := flag.Int("include-external-depth", 0, "How many imports to follow into third-party code")
+	types := flag.String("types", "", "Comma separated packages whose .d.ts declarations should be included")
+	withTests := flag.Bool("with-tests", false, "Include the tests of the collected files")
+	testsEntryOnly := flag.Bool("tests-entry-only", false, "Only include the tests of the entry file (implies -with-tests)")
+	withConfig := flag.Bool("with-config", false, "Include the nearest build and tooling config files in a separate section")
+	var changed optionalValueFlag
+	flag.Var(&changed, "changed", "Use the files changed relative to a git ref (default HEAD), and untracked files, as entry points")
+	withDiff := flag.Bool("with-diff", false, "Include the unified diff of each changed file (with -changed)")
+	rev := flag.String("rev", "", "Collect the files as they were at a git revision instead of from the working tree")
+	history := flag.Int("history", 0, "List the last N commits of each collected file")
+	blame := flag.String("blame", "", "Blame the lines around an error location, given as PATH:LINE")
+	blameContext := flag.Int("blame-context", 0, "How many lines either side of the -blame location to blame (default 5)")
+	noCache := flag.Bool("no-cache", false, "Don't read or update the cache of resolved imports")
+	jobs := flag.Int("jobs", 0, "How many files to read and parse at once (default the number of CPUs)")
+	reverse := flag.Bool("reverse", false, "Collect the files that import PATH, directly or indirectly, from the index built by fixfiles index")
+	outputFile := flag.String("o", "", "Write the output to a file instead of printing it")
+	copyOutput := flag.Bool("copy", false, "Copy the output to the clipboard instead of printing it")
+	watch := flag.Bool("watch", false, "Keep running and collect the files again whenever one of them or a config file changes")
+	format := flag.String("format", "text", "Output format: text, or json for a bundle that can be compared with fixfiles diff, or dot for the import graph of fixfiles index")
+
+	// The diff, index and impact subcommands take the same flags
+	subcommand := ""
+	arguments := os.Args[1:]
+	if len(arguments) > 0 && (arguments[0] == "diff" || arguments[0] == "index" || arguments[0] == "impact") {
+		subcommand = arguments[0]
+		arguments = arguments[1:]
+	}
+	flag.CommandLine.Parse(arguments)
 	args := flag.Args()
+	if subcommand == "index" && len(args) == 0 {
+		args = []string{"."}
+	}
 
-	if len(args) < 1 {
-		fmt.Println("Usage: fixfiles PATH")
+	if (len(args) < 1 && !changed.set) || (subcommand == "diff" && len(args) < 2) {
+		fmt.Println("Usage: fixfiles [flags] PATH")
+		fmt.Println("       fixfiles [flags] ARCHIVE PATH")
+		fmt.Println("       fixfiles -changed [REF]")
+		fmt.Println("       fixfiles diff [flags] OLD NEW [PATH]")
+		fmt.Println("       fixfiles index [flags] [DIR]")
+		fmt.Println("       fixfiles impact [flags] PATH")
 		fmt.Println("  PATH: Path to the file with the error")
+		fmt.Println("  ARCHIVE: .zip, .tar.gz or .tgz to collect from, with PATH inside it")
+		fmt.Println("  REF: Git ref to compare against, defaulting to HEAD")
+		fmt.Println("  OLD, NEW: Bundles saved with -format json, or git revisions to collect PATH at")
+		fmt.Println("  DIR: Directory in the project to index, defaulting to the working directory")
+		flag.PrintDefaults()
+		os.Exit(1)
+	}
+	if *format != "text" && *format != "json" && (*format != "dot" || subcommand != "index") {
+		fmt.Printf("Error: unknown format %s\n", *format)
 		os.Exit(1)
 	}
 
-	filePath := args[0]
-
-	// Get the absolute path
-	absPath, err := filepath.Abs(filePath)
-	if err != nil {
-		fmt.Printf("Error: could not get absolute path: %v\n", err)
+	var absPath, projectRoot, ref string
+	var err error
+	if subcommand == "diff" {
+		if isBundleFile(args[0]) && isBundleFile(args[1]) {
+			if err := PrintBundleDiff(args[0], args[1], "", "", *format); err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			return
+		}
+		if len(args) < 3 {
+			fmt.Println("Error: give the path of the file to collect at each revision")
+			os.Exit(1)
+		}
+		args = args[2:]
+	}
+	if changed.set && *rev != "" {
+		fmt.Println("Error: -changed and -rev can't be used together")
+		os.Exit(1)
+	}
+	if *watch && (subcommand != "" || *rev != "") {
+		fmt.Println("Error: -watch can't be used with -rev, fixfiles diff, fixfiles index or fixfiles impact")
 		os.Exit(1)
 	}
+	if (subcommand == "index" || subcommand == "impact" || *reverse) && *rev != "" {
+		fmt.Println("Error: the index is of the working tree, so it can't be used with -rev")
+		os.Exit(1)
+	}
+	if changed.set {
+		// Entry points come from the git repo in the working directory
+		ref = changed.value
+		if ref == "" && len(args) > 0 {
+			ref = args[0]
+		}
+		if ref == "" {
+			ref = "HEAD"
+		}
+		workDir, err := os.Getwd()
+		if err != nil {
+			fmt.Printf("Error: could not get working directory: %v\n", err)
+			os.Exit(1)
+		}
+		projectRoot, err = gitRepoRoot(workDir)
+		if err != nil {
+			fmt.Printf("Error: could not find git repository: %v\n", err)
+			os.Exit(1)
+		}
+	} else if isArchive(args[0]) {
+		if len(args) < 2 {
+			fmt.Println("Error: give the path of the file with the error inside the archive")
+			os.Exit(1)
+		}
+		if *rev != "" {
+			fmt.Println("Error: -rev can't be used with an archive")
+			os.Exit(1)
+		}
+		if subcommand == "index" || subcommand == "impact" || *reverse {
+			fmt.Println("Error: archives can't be indexed")
+			os.Exit(1)
+		}
+		if *watch {
+			fmt.Println("Error: -watch can't be used with an archive")
+			os.Exit(1)
+		}
 
-	// Find project root
-	projectRoot, err := FindProjectRoot(absPath)
-	if err != nil {
-		fmt.Printf("Error: could not find project root: %v\n", err)
+		archivePath, err := filepath.Abs(args[0])
+		if err != nil {
+			fmt.Printf("Error: could not get absolute path: %v\n", err)
+			os.Exit(1)
+		}
+		absPath, projectRoot, err = MountArchive(archivePath, args[1])
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	} else {
+		// Get the absolute path
+		absPath, err = filepath.Abs(args[0])
+		if err != nil {
+			fmt.Printf("Error: could not get absolute path: %v\n", err)
+			os.Exit(1)
+		}
+
+		// Read files from the object store when collecting an old revision
+		if *rev != "" {
+			repoRoot, err := gitRepoRootFor(absPath)
+			if err != nil {
+				fmt.Printf("Error: could not find git repository: %v\n", err)
+				os.Exit(1)
+			}
+			revFS, err := newGitRevFS(repoRoot, *rev)
+			if err != nil {
+				fmt.Printf("Error: %v\n", err)
+				os.Exit(1)
+			}
+			defer revFS.Close()
+			fileSystem = newMountFS(repoRoot, revFS, fileSystem)
+		}
+
+		// Find project root
+		projectRoot, err = FindProjectRoot(absPath)
+		if err != nil {
+			fmt.Printf("Error: could not find project root: %v\n", err)
+			os.Exit(1)
+		}
+	}
+
+	// Load project config, letting command line flags take precedence. It's loaded again when
+	// watching, as the config file can change.
+	loadSettings := func() error {
+		config, err = LoadConfig(projectRoot)
+		if err != nil {
+			return fmt.Errorf("could not load config: %v", err)
+		}
+		if *rails {
+			config.Rails = true
+		}
+		if *pairSources {
+			config.PairSources = true
+		}
+		if *includeAssets {
+			config.IncludeAssets = true
+		}
+		if *includeExternal != "" {
+			config.IncludeExternal = strings.Split(*includeExternal, ",")
+		}
+		if *includeExternalDepth > 0 {
+			config.IncludeExternalDepth = *includeExternalDepth
+		}
+		if *types != "" {
+			config.Types = strings.Split(*types, ",")
+		}
+		if *withTests {
+			config.WithTests = true
+		}
+		if *testsEntryOnly {
+			config.WithTests = true
+			config.TestsEntryOnly = true
+		}
+		if *withConfig {
+			config.WithConfig = true
+		}
+		if *withDiff {
+			config.WithDiff = true
+		}
+		if *history > 0 {
+			config.History = *history
+		}
+		if *blameContext > 0 {
+			config.BlameContext = *blameContext
+		}
+		if config.BlameContext <= 0 {
+			config.BlameContext = 5
+		}
+		if *jobs > 0 {
+			config.Jobs = *jobs
+		}
+		if config.Jobs > 0 {
+			workerCount = config.Jobs
+		}
+		if *noCache {
+			config.NoCache = true
+		}
+		if err := registerLanguageRules(config.Languages); err != nil {
+			return fmt.Errorf("invalid languages in config: %v", err)
+		}
+		return nil
+	}
+	if err := loadSettings(); err != nil {
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
 
-	// Process the file and its dependencies
-	results := make(map[string]string)
-	err = ProcessFile(absPath, projectRoot, results)
+	if subcommand == "diff" {
+		if err := PrintBundleDiff(flag.Arg(0), flag.Arg(1), absPath, projectRoot, *format); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// Cache resolved imports between runs, unless files are read from a revision or an archive
+	if !config.NoCache && *rev == "" && (changed.set || !isArchive(args[0])) {
+		importCache = LoadImportCache(importCachePath(projectRoot), projectRoot)
+	}
+
+	if subcommand == "index" {
+		if err := PrintIndex(projectRoot, *format); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		if err := importCache.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not save cache: %v\n", err)
+		}
+		return
+	}
+
+	if subcommand == "impact" {
+		index, err := LoadIndex(projectRoot)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		entries := []string{absPath}
+		if changed.set {
+			entries, err = changedSourceFiles(projectRoot, ref)
+			if err != nil {
+				fmt.Printf("Error: could not find changed files: %v\n", err)
+				os.Exit(1)
+			}
+			if len(entries) == 0 {
+				fmt.Printf("No changed files relative to %s\n", ref)
+				return
+			}
+		}
+		if err := PrintImpact(index, entries, *format); err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+		return
+	}
+
+	// collect processes the file and its dependencies and formats the output
+	collect := func() (map[string]string, string, error) {
+		results := make(map[string]string)
+		entries := []string{absPath}
+		if *reverse {
+			// Dependents come from the index rather than from parsing the project
+			index, err := LoadIndex(projectRoot)
+			if err != nil {
+				return nil, "", err
+			}
+			if changed.set {
+				entries, err = changedSourceFiles(projectRoot, ref)
+				if err != nil {
+					return nil, "", fmt.Errorf("could not find changed files: %v", err)
+				}
+				if len(entries) == 0 {
+					return results, fmt.Sprintf("No changed files relative to %s\n", ref), nil
+				}
+				if config.WithDiff {
+					for _, entry := range entries {
+						if diff := fileDiff(projectRoot, ref, entry); diff != "" {
+							fileDiffs[entry] = diff
+						}
+					}
+				}
+			}
+			IncludeDependents(index, entries, results)
+		} else if changed.set {
+			var err error
+			entries, err = ProcessChanged(projectRoot, ref, results, config.WithDiff)
+			if err != nil {
+				return nil, "", fmt.Errorf("could not find changed files: %v", err)
+			}
+			if len(entries) == 0 {
+				return results, fmt.Sprintf("No changed files relative to %s\n", ref), nil
+			}
+		} else if err := ProcessFile(absPath, projectRoot, results); err != nil {
+			return nil, "", fmt.Errorf("could not process file: %v", err)
+		}
+		if config.WithTests && config.TestsEntryOnly {
+			for _, entry := range entries {
+				IncludeTests(entry, projectRoot, results, true)
+			}
+		} else if config.WithTests {
+			IncludeTests(entries[0], projectRoot, results, false)
+		}
+		if config.WithConfig {
+			IncludeConfigFiles(projectRoot, results)
+		}
+		if config.History > 0 {
+			IncludeHistory(projectRoot, results, config.History, *rev)
+		}
+		if *blame != "" {
+			blamePath, line, err := parseLocation(*blame)
+			if err == nil {
+				blamePath, err = filepath.Abs(blamePath)
+			}
+			if err == nil {
+				err = IncludeBlame(projectRoot, blamePath, line, config.BlameContext, *rev)
+			}
+			if err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not blame %s: %v\n", *blame, err)
+			} else if _, ok := results[blamePath]; !ok {
+				fmt.Fprintf(os.Stderr, "Warning: %s is not one of the collected files, so its blame isn't shown\n", blamePath)
+			}
+		}
+
+		if err := importCache.Save(); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not save cache: %v\n", err)
+		}
+
+		// Format the results
+		if *format == "json" {
+			bundle := NewBundle(projectRoot, entries, results)
+			bundle.Revision = *rev
+			output, err := bundle.JSON()
+			if err != nil {
+				return nil, "", fmt.Errorf("could not encode bundle: %v", err)
+			}
+			return results, output, nil
+		}
+		return results, FormatResults(results), nil
+	}
+
+	// deliver writes the output to a file or the clipboard, or prints it
+	deliver := func(output string) error {
+		if *outputFile != "" {
+			if err := os.WriteFile(*outputFile, []byte(output), 0644); err != nil {
+				return fmt.Errorf("could not write %s: %v", *outputFile, err)
+			}
+		}
+		if *copyOutput {
+			if err := CopyToClipboard(output); err != nil {
+				return fmt.Errorf("could not copy to clipboard: %v", err)
+			}
+		}
+		if *outputFile == "" && !*copyOutput {
+			fmt.Print(output)
+		}
+		return nil
+	}
+
+	// Watch mode keeps the files it loaded, so only the files a change affects are read again, and
+	// a cache in memory even when it isn't saved, so only changed files are parsed again after a
+	// manifest changes
+	if *watch {
+		loadedFiles = make(map[fileJob]rememberedFile)
+		if importCache == nil {
+			importCache = LoadImportCache("", projectRoot)
+		}
+	}
+
+	results, output, err := collect()
 	if err != nil {
-		fmt.Printf("Error processing file: %v\n", err)
+		fmt.Printf("Error: %v\n", err)
 		os.Exit(1)
 	}
-
-	// Format and write results to file
-	PrintResults(results)
+	if err := deliver(output); err != nil {
+		fmt.Printf("Error: %v\n", err)
+		os.Exit(1)
+	}
+	if *watch {
+		err := Watch(projectRoot, results, func() (map[string]string, string, error) {
+			if err := loadSettings(); err != nil {
+				return nil, "", err
+			}
+			resetCollection()
+			return collect()
+		}, deliver, nil)
+		if err != nil {
+			fmt.Printf("Error: %v\n", err)
+			os.Exit(1)
+		}
+	}
 }
@@ -0,0 +1,442 @@
+package main
+
+import (
+	"bytes"
+	"encoding/json"
+	"os"
+	"path/filepath"
+	"regexp"
+	"sort"
+	"strings"
+)
+
+// packageJSON is the subset of package.json fields used for resolution
+type packageJSON struct {
+	Name                 string            `json:"name"`
+	Main                 string            `json:"main"`
+	Module               string            `json:"module"`
+	Types                string            `json:"types"`
+	Typings              string            `json:"typings"`
+	Exports              json.RawMessage   `json:"exports"`
+	Imports              json.RawMessage   `json:"imports"`
+	Workspaces           json.RawMessage   `json:"workspaces"`
+	Dependencies         map[string]string `json:"dependencies"`
+	DevDependencies      map[string]string `json:"devDependencies"`
+	PeerDependencies     map[string]string `json:"peerDependencies"`
+	OptionalDependencies map[string]string `json:"optionalDependencies"`
+}
+
+// Conditions tried when resolving exports and imports, most useful for reading source first
+var nodeConditions = []string{"source", "development", "import", "module", "require", "node", "default", "types", "browser"}
+
+// Core modules that never resolve to project files
+var nodeBuiltinModules = []string{
+	"assert", "async_hooks", "buffer", "child_process", "cluster", "console", "crypto", "dgram",
+	"diagnostics_channel", "dns", "events", "fs", "http", "http2", "https", "inspector", "module",
+	"net", "os", "path", "perf_hooks", "process", "querystring", "readline", "stream",
+	"string_decoder", "timers", "tls", "tty", "url", "util", "v8", "vm", "worker_threads", "zlib",
+}
+
+// Extensions of files that use Node module resolution
+var nodeExtensions = []string{".js", ".jsx", ".ts", ".tsx", ".mjs", ".cjs", ".mts", ".cts", ".d.ts"}
+
+// Matches an entry of a YAML list
+var yamlListItemPattern = regexp.MustCompile(`^\s*-\s*['"]?([^'"\s#]+)['"]?`)
+
+// Parsed package.json files keyed by path; nil marks a missing or invalid file
+var packageJSONs = make(map[string]*packageJSON)
+
+// Workspace package directories keyed by package name, per project root
+var workspacePackages = make(map[string]map[string]string)
+
+// resolveNodeSpecifier resolves a bare or #subpath specifier the way Node does. handled is false when the
+// specifier isn't a known package and should fall back to the generic rules; a handled specifier with an
+// empty path is a built-in or third-party package that should be skipped.
+func resolveNodeSpecifier(specifier string, fromFile string, projectRoot string) (resolved string, handled bool) {
+	if strings.HasPrefix(specifier, "node:") || containsString(nodeBuiltinModules, strings.SplitN(specifier, "/", 2)[0]) {
+		return "", true
+	}
+
+	// #subpath imports are private to the nearest package
+	if strings.HasPrefix(specifier, "#") {
+		pkgDir, pkg := nearestPackageJSON(filepath.Dir(fromFile), projectRoot)
+		if pkg == nil {
+			return "", true
+		}
+		target, ok := resolvePackageMap(pkg.Imports, specifier, false)
+		if !ok {
+			return "", true
+		}
+		if !strings.HasPrefix(target, "./") {
+			// An import can map to another package
+			return resolveNodeSpecifier(target, fromFile, projectRoot)
+		}
+		return resolveWorkspaceFile(filepath.Join(pkgDir, target)), true
+	}
+
+	name, subpath := splitPackageSpecifier(specifier)
+
+	// A package may import itself by name
+	if pkgDir, pkg := nearestPackageJSON(filepath.Dir(fromFile), projectRoot); pkg != nil && pkg.Name == name {
+		return resolvePackageEntry(pkgDir, pkg, subpath), true
+	}
+
+	if pkgDir, ok := workspacePackagesFor(projectRoot)[name]; ok {
+		return resolvePackageEntry(pkgDir, readPackageJSON(filepath.Join(pkgDir, "package.json")), subpath), true
+	}
+
+	// Packages in node_modules are third-party unless they link back into the project
+	if pkgDir := findInNodeModules(name, filepath.Dir(fromFile), projectRoot); pkgDir != "" {
+		realDir, err := realPath(pkgDir)
+		if err == nil && !isInsideNodeModules(realDir) {
+			return resolvePackageEntry(realDir, readPackageJSON(filepath.Join(realDir, "package.json")), subpath), true
+		}
+		if externalAllowed(name) {
+			return resolvePackageEntry(pkgDir, readPackageJSON(filepath.Join(pkgDir, "package.json")), subpath), true
+		}
+		return "", true
+	}
+
+	// Declared dependencies are third-party even when they aren't installed
+	if _, pkg := nearestPackageJSON(filepath.Dir(fromFile), projectRoot); pkg != nil && pkg.dependsOn(name) {
+		return "", true
+	}
+
+	return "", false
+}
+
+// isBareSpecifier reports whether a specifier names a package rather than a path or project alias
+func isBareSpecifier(specifier string) bool {
+	return !strings.HasPrefix(specifier, ".") && !strings.HasPrefix(specifier, "/") &&
+		!strings.HasPrefix(specifier, "~") && !strings.HasPrefix(specifier, "@/")
+}
+
+// splitPackageSpecifier splits "@scope/name/sub/path" into "@scope/name" and "./sub/path"
+func splitPackageSpecifier(specifier string) (string, string) {
+	parts := strings.Split(specifier, "/")
+	n := 1
+	if strings.HasPrefix(specifier, "@") && len(parts) > 1 {
+		n = 2
+	}
+	name := strings.Join(parts[:n], "/")
+	subpath := "."
+	if len(parts) > n {
+		subpath = "./" + strings.Join(parts[n:], "/")
+	}
+	return name, subpath
+}
+
+// resolvePackageEntry resolves a subpath of a first-party package through exports, then main/module/types
+func resolvePackageEntry(pkgDir string, pkg *packageJSON, subpath string) string {
+	if pkg != nil && len(pkg.Exports) > 0 {
+		if target, ok := resolvePackageMap(pkg.Exports, subpath, true); ok {
+			return resolveWorkspaceFile(filepath.Join(pkgDir, target))
+		}
+		// Subpaths not listed in exports are not importable
+		return ""
+	}
+
+	if subpath == "." {
+		if pkg != nil {
+			for _, field := range []string{pkg.Module, pkg.Main, pkg.Types, pkg.Typings} {
+				if field == "" {
+					continue
+				}
+				if resolved := resolveWorkspaceFile(filepath.Join(pkgDir, field)); resolved != "" {
+					return resolved
+				}
+			}
+		}
+		return resolveWorkspaceFile(filepath.Join(pkgDir, "index"))
+	}
+
+	return resolveWorkspaceFile(filepath.Join(pkgDir, subpath))
+}
+
+// resolvePackageMap looks up a subpath in an exports or imports field, including * patterns and conditions.
+// A string or conditions object for exports is shorthand for the "." entry.
+func resolvePackageMap(raw json.RawMessage, subpath string, isExports bool) (string, bool) {
+	if len(raw) == 0 {
+		return "", false
+	}
+
+	var entries map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &entries); err != nil || !hasSubpathKeys(entries, isExports) {
+		if isExports && subpath == "." {
+			return resolvePackageTarget(raw, "")
+		}
+		return "", false
+	}
+
+	if target, ok := entries[subpath]; ok {
+		return resolvePackageTarget(target, "")
+	}
+
+	// The longest matching "prefix*suffix" pattern wins
+	var keys []string
+	for key := range entries {
+		if strings.Count(key, "*") == 1 {
+			keys = append(keys, key)
+		}
+	}
+	sort.Slice(keys, func(i, j int) bool { return len(keys[i]) > len(keys[j]) })
+
+	for _, key := range keys {
+		star := strings.Index(key, "*")
+		prefix, suffix := key[:star], key[star+1:]
+		if strings.HasPrefix(subpath, prefix) && strings.HasSuffix(subpath, suffix) && len(subpath) >= len(prefix)+len(suffix) {
+			return resolvePackageTarget(entries[key], subpath[len(prefix):len(subpath)-len(suffix)])
+		}
+	}
+
+	return "", false
+}
+
+// hasSubpathKeys reports whether a map is keyed by subpaths (./x or #x) rather than conditions
+func hasSubpathKeys(entries map[string]json.RawMessage, isExports bool) bool {
+	for key := range entries {
+		if (isExports && strings.HasPrefix(key, ".")) || (!isExports && strings.HasPrefix(key, "#")) {
+			return true
+		}
+	}
+	return false
+}
+
+// resolvePackageTarget resolves a target string, array or conditions object, substituting * with match.
+// A null target blocks the subpath, so alternatives and conditions after it aren't tried.
+func resolvePackageTarget(raw json.RawMessage, match string) (string, bool) {
+	target, matched := matchPackageTarget(raw, match)
+	return target, matched && target != ""
+}
+
+// matchPackageTarget returns the target of the first alternative or condition that matches, or ""
+// if that's null, and whether one matched
+func matchPackageTarget(raw json.RawMessage, match string) (string, bool) {
+	if string(bytes.TrimSpace(raw)) == "null" {
+		return "", true
+	}
+
+	var target string
+	if err := json.Unmarshal(raw, &target); err == nil {
+		return strings.ReplaceAll(target, "*", match), true
+	}
+
+	var alternatives []json.RawMessage
+	if err := json.Unmarshal(raw, &alternatives); err == nil {
+		for _, alternative := range alternatives {
+			if resolved, ok := matchPackageTarget(alternative, match); ok {
+				return resolved, true
+			}
+		}
+		return "", false
+	}
+
+	var conditions map[string]json.RawMessage
+	if err := json.Unmarshal(raw, &conditions); err == nil {
+		for _, condition := range nodeConditions {
+			if value, ok := conditions[condition]; ok {
+				if resolved, ok := matchPackageTarget(value, match); ok {
+					return resolved, true
+				}
+			}
+		}
+	}
+
+	return "", false
+}
+
+// resolveWorkspaceFile finds the file for a package target. Targets often point at build output that
+// doesn't exist in a checkout, so dist/, lib/, build/ and out/ are also tried as src/.
+func resolveWorkspaceFile(path string) string {
+	if resolved := findModuleFile(path); resolved != "" {
+		return resolved
+	}
+
+	sep := string(filepath.Separator)
+	for _, outDir := range []string{"dist", "lib", "build", "out"} {
+		marker := sep + outDir + sep
+		if i := strings.LastIndex(path, marker); i >= 0 {
+			source := path[:i] + sep + "src" + sep + path[i+len(marker):]
+			source = strings.TrimSuffix(source, ".d.ts")
+			source = strings.TrimSuffix(source, filepath.Ext(source))
+			if resolved := findModuleFile(source); resolved != "" {
+				return resolved
+			}
+		}
+	}
+
+	return ""
+}
+
+// findModuleFile tries a path as-is, with each JS/TS extension, and as a directory with an index file
+func findModuleFile(path string) string {
+	if info, err := statFile(path); err == nil && !info.IsDir() {
+		return path
+	}
+	for _, ext := range nodeExtensions {
+		if _, err := statFile(path + ext); err == nil {
+			return path + ext
+		}
+	}
+	for _, ext := range nodeExtensions {
+		indexPath := filepath.Join(path, "index"+ext)
+		if _, err := statFile(indexPath); err == nil {
+			return indexPath
+		}
+	}
+	return ""
+}
+
+// nearestPackageJSON finds the closest package.json at or above dir, stopping at the project root
+func nearestPackageJSON(dir string, projectRoot string) (string, *packageJSON) {
+	for {
+		if pkg := readPackageJSON(filepath.Join(dir, "package.json")); pkg != nil {
+			return dir, pkg
+		}
+		if dir == projectRoot || filepath.Dir(dir) == dir {
+			return "", nil
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// readPackageJSON parses a package.json file, caching the result
+func readPackageJSON(path string) *packageJSON {
+	if pkg, ok := cacheLoad(packageJSONs, path); ok {
+		return pkg
+	}
+
+	var pkg *packageJSON
+	if content, err := readFile(path); err == nil {
+		pkg = &packageJSON{}
+		if err := json.Unmarshal(content, pkg); err != nil {
+			pkg = nil
+		}
+	}
+
+	cacheStore(packageJSONs, path, pkg)
+	return pkg
+}
+
+// dependsOn reports whether a package declares a dependency on name
+func (p *packageJSON) dependsOn(name string) bool {
+	for _, deps := range []map[string]string{p.Dependencies, p.DevDependencies, p.PeerDependencies, p.OptionalDependencies} {
+		if _, ok := deps[name]; ok {
+			return true
+		}
+	}
+	return false
+}
+
+// findInNodeModules looks for a package in node_modules directories from dir up to the project root
+func findInNodeModules(name string, dir string, projectRoot string) string {
+	for {
+		pkgDir := filepath.Join(dir, "node_modules", name)
+		if _, err := statFile(pkgDir); err == nil {
+			return pkgDir
+		}
+		if dir == projectRoot || filepath.Dir(dir) == dir {
+			return ""
+		}
+		dir = filepath.Dir(dir)
+	}
+}
+
+// isInsideNodeModules reports whether a path is inside a node_modules directory
+func isInsideNodeModules(path string) bool {
+	return strings.Contains(path+string(filepath.Separator), string(filepath.Separator)+"node_modules"+string(filepath.Separator))
+}
+
+// workspacePackagesFor maps the names of npm, yarn and pnpm workspace packages to their directories
+func workspacePackagesFor(projectRoot string) map[string]string {
+	if packages, ok := cacheLoad(workspacePackages, projectRoot); ok {
+		return packages
+	}
+
+	var patterns []string
+	if pkg := readPackageJSON(filepath.Join(projectRoot, "package.json")); pkg != nil && len(pkg.Workspaces) > 0 {
+		// workspaces is either a list of globs or, for yarn, an object with a packages list
+		if err := json.Unmarshal(pkg.Workspaces, &patterns); err != nil {
+			var yarnWorkspaces struct {
+				Packages []string `json:"packages"`
+			}
+			json.Unmarshal(pkg.Workspaces, &yarnWorkspaces)
+			patterns = yarnWorkspaces.Packages
+		}
+	}
+	if content, err := readFile(filepath.Join(projectRoot, "pnpm-workspace.yaml")); err == nil {
+		patterns = append(patterns, parsePnpmPackages(string(content))...)
+	}
+
+	var includes, excludes []string
+	for _, pattern := range patterns {
+		pattern = strings.TrimSuffix(strings.TrimPrefix(pattern, "./"), "/")
+		if strings.HasPrefix(pattern, "!") {
+			excludes = append(excludes, strings.TrimPrefix(pattern, "!"))
+		} else {
+			includes = append(includes, pattern)
+		}
+	}
+
+	packages := make(map[string]string)
+	if len(includes) > 0 {
+		walkDir(projectRoot, func(path string, d os.DirEntry, err error) error {
+			if err != nil || !d.IsDir() {
+				return nil
+			}
+			if d.Name() == "node_modules" || d.Name() == ".git" {
+				return filepath.SkipDir
+			}
+			rel, _ := filepath.Rel(projectRoot, path)
+			rel = filepath.ToSlash(rel)
+			if matchesAnyGlob(includes, rel) && !matchesAnyGlob(excludes, rel) {
+				if pkg := readPackageJSON(filepath.Join(path, "package.json")); pkg != nil && pkg.Name != "" {
+					packages[pkg.Name] = path
+				}
+			}
+			return nil
+		})
+	}
+
+	cacheStore(workspacePackages, projectRoot, packages)
+	return packages
+}
+
+// parsePnpmPackages returns the globs listed under packages: in a pnpm-workspace.yaml file
+func parsePnpmPackages(content string) []string {
+	return yamlSectionItems(content, "packages")
+}
+
+// yamlSectionItems returns the list items below a top-level key
+func yamlSectionItems(content string, key string) []string {
+	var items []string
+	for _, line := range yamlSection(content, key) {
+		if match := yamlListItemPattern.FindStringSubmatch(line); match != nil {
+			items = append(items, match[1])
+		}
+	}
+	return items
+}
+
+// yamlSection returns the non-empty lines nested below a top-level key
+func yamlSection(content string, key string) []string {
+	var lines []string
+	inSection := false
+
+	for _, line := range strings.Split(content, "\n") {
+		trimmed := strings.TrimSpace(line)
+		if trimmed == "" || strings.HasPrefix(trimmed, "#") {
+			continue
+		}
+		// A new top-level key ends the section
+		if line[0] != ' ' && line[0] != '\t' && line[0] != '-' {
+			inSection = strings.HasPrefix(trimmed, key+":")
+			continue
+		}
+		if inSection {
+			lines = append(lines, line)
+		}
+	}
+
+	return lines
+}
@@ -0,0 +1,113 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestNodeWorkspaceResolution tests exports, imports and workspace package resolution
+func TestNodeWorkspaceResolution(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "node-resolution-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"package.json": `{"name": "monorepo", "private": true, "workspaces": ["packages/*", "apps/*"]}`,
+		"packages/ui/package.json": `{
+  "name": "@acme/ui",
+  "exports": {
+    ".": {"types": "./dist/index.d.ts", "import": "./dist/index.js"},
+    "./button": "./src/button.tsx",
+    "./icons/*": "./src/icons/*.tsx",
+    "./*": "./src/*.tsx",
+    "./internal/*": null,
+    "./legacy": {"import": null, "default": "./src/legacy.tsx"}
+  }
+}`,
+		"packages/ui/src/index.ts":            "export * from './button';",
+		"packages/ui/src/button.tsx":          "export const Button = () => null;",
+		"packages/ui/src/icons/star.tsx":      "export const Star = () => null;",
+		"packages/ui/src/internal/secret.tsx": "export const secret = 1;",
+		"packages/ui/src/legacy.tsx":          "export const legacy = 1;",
+		"packages/ui/index.ts":                "export * from './src';",
+		"packages/utils/package.json":         `{"name": "@acme/utils", "main": "lib/index.js"}`,
+		"packages/utils/lib/index.js":         "module.exports = {};",
+		"apps/web/package.json": `{
+  "name": "web",
+  "imports": {"#internal/*": "./src/internal/*.ts"},
+  "dependencies": {"react": "^19.0.0"}
+}`,
+		"apps/web/src/internal/config.ts":  "export const config = {};",
+		"node_modules/lodash/package.json": `{"name": "lodash", "main": "lodash.js"}`,
+		"node_modules/lodash/lodash.js":    "",
+		"apps/web/src/app.tsx":             "",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	appPath := filepath.Join(tempDir, "apps", "web", "src", "app.tsx")
+	app := []byte(`
+import fs from 'node:fs';
+import path from 'path';
+import React from 'react';
+import debounce from 'lodash';
+import { Button } from '@acme/ui/button';
+import { Star } from '@acme/ui/icons/star';
+import * as ui from '@acme/ui';
+import { secret } from '@acme/ui/internal/secret';
+import { legacy } from '@acme/ui/legacy';
+import { merge } from '@acme/utils';
+import { config } from '#internal/config';
+`)
+
+	imports := extractImportsFromContent(appPath, ".tsx", app, tempDir)
+	expected := []string{
+		"packages/ui/src/button.tsx",
+		"packages/ui/src/icons/star.tsx",
+		"packages/ui/src/index.ts",
+		"packages/utils/lib/index.js",
+		"apps/web/src/internal/config.ts",
+	}
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for _, path := range expected {
+		if !containsString(imports, filepath.Join(tempDir, path)) {
+			t.Errorf("Expected to find %s in imports", path)
+		}
+	}
+}
+
+// TestParsePnpmPackages tests reading the packages list of a pnpm workspace
+func TestParsePnpmPackages(t *testing.T) {
+	content := `packages:
+  - 'packages/*'
+  - "apps/**"
+  # comment
+  - '!**/test/**'
+onlyBuiltDependencies:
+  - esbuild
+`
+	patterns := parsePnpmPackages(content)
+	expected := []string{"packages/*", "apps/**", "!**/test/**"}
+
+	if len(patterns) != len(expected) {
+		t.Fatalf("Expected %d patterns, got %d: %v", len(expected), len(patterns), patterns)
+	}
+	for i := range expected {
+		if patterns[i] != expected[i] {
+			t.Errorf("Pattern %d: got %q, want %q", i, patterns[i], expected[i])
+		}
+	}
+}
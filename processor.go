@@ -2,33 +2,265 @@ package main
 
 import (
 	"fmt"
-	"io/ioutil"
 	"os"
 	"path/filepath"
 	"regexp"
+	"runtime"
 	"strings"
+	"sync"
 )
 
-// ProcessFile analyzes a file and its dependencies recursively
-func ProcessFile(filePath string, projectRoot string, results map[string]string) error {
-	// Normalize the path
-	filePath = filepath.Clean(filePath)
+// Resolved imports of each processed file, whether or not they were followed
+var importGraph = make(map[string][]string)
+
+// Directories each processed file's imports were found or looked for in
+var importDirectories = make(map[string]map[string]int64)
+
+// resetCollection forgets the files collected so far and everything cached about the project, so
+// another set of files can be collected, such as the same project at a different revision
+func resetCollection() {
+	processedFiles = make(map[string]struct{})
+	externalFiles = make(map[string]string)
+	configFiles = make(map[string]struct{})
+	importGraph = make(map[string][]string)
+	importDirectories = make(map[string]map[string]int64)
+	fileDiffs = make(map[string]string)
+	fileHistory = make(map[string][]string)
+	fileBlame = make(map[string]*blameSummary)
+
+	cIncludePaths = make(map[string]map[string]includePaths)
+	csharpProjects = make(map[string]*csharpProject)
+	goModFiles = make(map[string]*goModFile)
+	graphqlFragments = make(map[string]map[string]string)
+	packageJSONs = make(map[string]*packageJSON)
+	workspacePackages = make(map[string]map[string]string)
+	protoRoots = make(map[string][]string)
+	pythonSitePackages = make(map[string][]string)
+	rubyLoadPaths = make(map[string][]string)
+	tsTypeRoots = make(map[string][]string)
+	importCache.newRun()
+}
+
+// Guards the caches resolvers keep about the project, which workers share
+var cacheMu sync.Mutex
 
+// cacheLoad looks up a value in one of the resolver caches
+func cacheLoad[K comparable, V any](cache map[K]V, key K) (V, bool) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	value, ok := cache[key]
+	return value, ok
+}
+
+// cacheStore adds a value to one of the resolver caches. Values are computed without holding the
+// lock, so two workers may compute the same one; they must not be changed once stored.
+func cacheStore[K comparable, V any](cache map[K]V, key K, value V) {
+	cacheMu.Lock()
+	defer cacheMu.Unlock()
+	cache[key] = value
+}
+
+// ProcessFile analyzes a file and its dependencies recursively. Files are read and parsed by a
+// pool of workers, one level of imports at a time, and merged in import order so the files
+// collected don't depend on which worker finishes first.
+func ProcessFile(filePath string, projectRoot string, results map[string]string) error {
 	// Skip if we've already processed this file
+	filePath = filepath.Clean(filePath)
 	if _, exists := processedFiles[filePath]; exists {
 		return nil
 	}
 
+	frontier := []fileJob{{path: filePath}}
+	for level := 0; len(frontier) > 0; level++ {
+		var next []fileJob
+		queued := make(map[string]struct{})
+
+		for _, file := range loadFiles(frontier, projectRoot) {
+			if file.err != nil {
+				if level == 0 {
+					return file.err
+				}
+				fmt.Fprintf(os.Stderr, "Warning: could not process import %s: %v\n", file.job.path, file.err)
+				continue
+			}
+			if file.skip {
+				continue
+			}
+			// Different imports can find the same file, such as ./utils and ./utils/index.ts
+			if _, exists := processedFiles[file.path]; exists {
+				continue
+			}
+			processedFiles[file.path] = struct{}{}
+			results[file.path] = file.content
+			if file.asset {
+				continue
+			}
+
+			if pkg := externalPackageOf(file.path, projectRoot); pkg != "" {
+				externalFiles[file.path] = pkg
+			}
+			if file.importErr != nil {
+				fmt.Fprintf(os.Stderr, "Warning: failed to extract imports from %s: %v\n", file.path, file.importErr)
+				// Continue even if we can't extract imports
+			}
+			importGraph[file.path] = file.imports
+			importDirectories[file.path] = file.dirs
+
+			for _, importPath := range file.imports {
+				// Third-party files are only followed for allowed packages and up to the configured depth
+				depth := 0
+				if pkg := externalPackageOf(importPath, projectRoot); pkg != "" {
+					depth = file.job.externalDepth + 1
+					// Requested declarations are followed through the whole package, as they're only type information
+					if declarationsAllowed(importPath, pkg) {
+						depth = file.job.externalDepth
+					} else if !externalAllowed(pkg) || depth > externalDepthLimit() {
+						continue
+					}
+				}
+
+				importPath = filepath.Clean(importPath)
+				if _, exists := processedFiles[importPath]; exists {
+					continue
+				}
+				if _, exists := queued[importPath]; exists {
+					continue
+				}
+				queued[importPath] = struct{}{}
+				next = append(next, fileJob{path: importPath, externalDepth: depth})
+			}
+		}
+
+		frontier = next
+	}
+
+	return nil
+}
+
+// Maximum number of files read and parsed at once
+var workerCount = runtime.GOMAXPROCS(0)
+
+// fileJob is a file to collect that was found externalDepth imports deep into third-party code
+type fileJob struct {
+	path          string
+	externalDepth int
+}
+
+// loadedFile is a file read by a worker, with the imports found in it
+type loadedFile struct {
+	job fileJob
+	// path is where the file was found, after trying extensions and index files
+	path      string
+	content   string
+	imports   []string
+	importErr error
+	// dirs holds the modification times of the directories its imports were found or looked for in
+	dirs map[string]int64
+	// asset marks an image, font or media file whose content is a description
+	asset bool
+	// skip marks directories and unsupported files, which aren't collected
+	skip bool
+	err  error
+}
+
+// Files loaded so far, reused by later collections until they're forgotten, or nil to load files
+// every time. Watch mode keeps them between runs so only the files a change affects are read again.
+var loadedFiles map[fileJob]rememberedFile
+
+// rememberedFile is a loaded file kept for later collections
+type rememberedFile struct {
+	loadedFile
+	// lookupDirs are the directories where the file was found and its imports were looked for
+	lookupDirs map[string]int64
+}
+
+// loadFiles reads and parses files with a bounded pool of workers. The files are returned in the
+// order of the jobs.
+func loadFiles(jobs []fileJob, projectRoot string) []loadedFile {
+	loaded := make([]loadedFile, len(jobs))
+	var pending []int
+	for index, job := range jobs {
+		if file, ok := loadedFiles[job]; ok {
+			loaded[index] = file.loadedFile
+		} else {
+			pending = append(pending, index)
+		}
+	}
+	indexes := make(chan int)
+
+	var wg sync.WaitGroup
+	for i := 0; i < min(max(workerCount, 1), len(pending)); i++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			for index := range indexes {
+				loaded[index] = loadFile(jobs[index], projectRoot)
+			}
+		}()
+	}
+	for _, index := range pending {
+		indexes <- index
+	}
+	close(indexes)
+	wg.Wait()
+
+	// Files that couldn't be loaded are tried again next time
+	if loadedFiles != nil {
+		for _, index := range pending {
+			if file := loaded[index]; file.err == nil {
+				dirs := resolutionDirectories([]string{file.job.path, file.path})
+				for dir, modTime := range file.dirs {
+					dirs[dir] = modTime
+				}
+				loadedFiles[file.job] = rememberedFile{loadedFile: file, lookupDirs: dirs}
+			}
+		}
+	}
+
+	return loaded
+}
+
+// forgetLoadedFiles drops the loaded files a change could affect: the files edited, and for each
+// file created, removed or renamed, the files found or whose imports were looked up in its directory
+func forgetLoadedFiles(edited []string, moved []string) {
+	changed := make(map[string]struct{})
+	for _, path := range append(edited, moved...) {
+		changed[path] = struct{}{}
+	}
+	dirs := make(map[string]struct{})
+	for _, path := range moved {
+		dirs[filepath.Dir(path)] = struct{}{}
+	}
+
+	for job, file := range loadedFiles {
+		_, jobChanged := changed[job.path]
+		_, fileChanged := changed[file.path]
+		forget := jobChanged || fileChanged
+		for dir := range file.lookupDirs {
+			if _, ok := dirs[dir]; ok {
+				forget = true
+			}
+		}
+		if forget {
+			delete(loadedFiles, job)
+		}
+	}
+}
+
+// loadFile finds a file, reads it once and extracts its imports
+func loadFile(job fileJob, projectRoot string) loadedFile {
+	file := loadedFile{job: job, path: job.path}
+
 	// Check if the file exists
-	info, err := os.Stat(filePath)
+	info, err := statFile(file.path)
 	if err != nil {
 		// Try to find the file with extensions if it doesn't have one
 		foundFile := false
-		if filepath.Ext(filePath) == "" {
-			for ext := range supportedExtensions {
-				testPath := filePath + ext
-				if _, err := os.Stat(testPath); err == nil {
-					filePath = testPath
+		if filepath.Ext(file.path) == "" {
+			for _, ext := range candidateExtensions() {
+				testPath := file.path + ext
+				if _, err := statFile(testPath); err == nil {
+					file.path = testPath
 					foundFile = true
 					break
 				}
@@ -36,114 +268,83 @@ func ProcessFile(filePath string, projectRoot string, results map[string]string)
 		}
 
 		if !foundFile {
-			// Try index.* files for directories
-			if dirInfo, dirErr := os.Stat(filePath); dirErr == nil && dirInfo.IsDir() {
-				for ext := range supportedExtensions {
-					indexPath := filepath.Join(filePath, "index"+ext)
-					if _, err := os.Stat(indexPath); err == nil {
-						filePath = indexPath
-						foundFile = true
-						break
-					}
-				}
-			}
-		}
-
-		if !foundFile {
-			return fmt.Errorf("file not found: %s", filePath)
+			file.err = fmt.Errorf("file not found: %s", file.path)
+			return file
 		}
 
-		info, err = os.Stat(filePath)
+		info, err = statFile(file.path)
 		if err != nil {
-			return err
+			file.err = err
+			return file
 		}
 	}
 
 	// Skip directories
 	if info.IsDir() {
-		return nil
+		file.skip = true
+		return file
 	}
 
 	// Skip unsupported file types
-	fileExt := filepath.Ext(filePath)
-	if _, supported := supportedExtensions[fileExt]; !supported {
-		return nil
+	fileExt := fileExtension(file.path)
+	if !isSupportedExtension(fileExt) {
+		// Referenced images, fonts and media are only included on request
+		if config.IncludeAssets && isAssetFile(file.path) {
+			content, err := readFile(file.path)
+			if err != nil {
+				file.err = err
+				return file
+			}
+			file.asset = true
+			file.content = describeAsset(file.path, content)
+			return file
+		}
+		file.skip = true
+		return file
 	}
 
-	// Mark as processed
-	processedFiles[filePath] = struct{}{}
-
 	// Read file content
-	content, err := ioutil.ReadFile(filePath)
+	content, err := readFile(file.path)
 	if err != nil {
-		return err
+		file.err = err
+		return file
 	}
+	file.content = string(content)
 
-	// Add to results
-	results[filePath] = string(content)
-
-	// Extract imports
-	imports, err := ExtractImports(filePath, projectRoot)
-	if err != nil {
-		fmt.Fprintf(os.Stderr, "Warning: failed to extract imports from %s: %v\n", filePath, err)
-		// Continue even if we can't extract imports
+	// Extract imports from the content already read, unless they're cached
+	if languageFor(fileExt) == nil {
+		file.importErr = fmt.Errorf("unsupported file extension: %s", fileExt)
+	} else if imports, dirs, ok := importCache.lookup(file.path, info, content); ok {
+		file.imports, file.dirs = imports, dirs
+	} else {
+		imports, searched := resolveSourceImports(&SourceFile{Path: file.path, Ext: fileExt, Content: content, ProjectRoot: projectRoot})
+		file.imports, file.dirs = imports, resolutionDirectories(append(append([]string{}, imports...), searched...))
+		importCache.store(file.path, info, content, file.imports, file.dirs)
 	}
 
-	// Process each imported file recursively
-	for _, importPath := range imports {
-		err = ProcessFile(importPath, projectRoot, results)
-		if err != nil {
-			fmt.Fprintf(os.Stderr, "Warning: could not process import %s: %v\n", importPath, err)
-		}
-	}
-
-	return nil
+	return file
 }
 
 // ExtractImports finds all import statements in a file
 func ExtractImports(filePath string, projectRoot string) ([]string, error) {
-	fileExt := filepath.Ext(filePath)
-	patterns, ok := importPatterns[fileExt]
-	if !ok {
+	fileExt := fileExtension(filePath)
+	if languageFor(fileExt) == nil {
 		return nil, fmt.Errorf("unsupported file extension: %s", fileExt)
 	}
 
-	content, err := ioutil.ReadFile(filePath)
+	content, err := readFile(filePath)
 	if err != nil {
 		return nil, err
 	}
 
-	var imports []string
-	fileDir := filepath.Dir(filePath)
-
-	for _, pattern := range patterns {
-		matches := pattern.FindAllSubmatch(content, -1)
-		for _, match := range matches {
-			if len(match) >= 2 {
-				importPath := string(match[1])
-
-				// Try to resolve the import path to an actual file
-				resolvedPath, err := ResolveImportPath(importPath, fileDir, projectRoot)
-				if err != nil {
-					fmt.Fprintf(os.Stderr, "Warning: could not resolve import path %s: %v\n", importPath, err)
-					continue
-				}
-
-				// Check if this is a relative path that might be part of the project
-				if strings.HasPrefix(importPath, ".") ||
-					strings.HasPrefix(importPath, "/") ||
-					strings.HasPrefix(importPath, "@") ||
-					strings.HasPrefix(importPath, "~") ||
-					// For local imports without special prefixes
-					(!strings.Contains(importPath, "/") && !isBuiltinModule(importPath, fileExt)) {
-					imports = append(imports, resolvedPath)
-				}
-				// Skip external imports (node_modules, npm packages, etc.)
-			}
-		}
-	}
+	return extractImportsFromContent(filePath, fileExt, content, projectRoot), nil
+}
 
-	return imports, nil
+// extractImportsFromContent finds the imports in content written in the language of fileExt.
+// The extension is passed separately so embedded blocks, such as a <script lang="ts"> in a
+// Vue component, can be handled as the language they contain.
+func extractImportsFromContent(filePath string, fileExt string, content []byte, projectRoot string) []string {
+	return extractSourceImports(&SourceFile{Path: filePath, Ext: fileExt, Content: content, ProjectRoot: projectRoot})
 }
 
 // isBuiltinModule checks if an import refers to a built-in module
@@ -1,8 +1,10 @@
 package main
 
 import (
+	"fmt"
 	"os"
 	"path/filepath"
+	"strings"
 	"testing"
 )
 
@@ -143,3 +145,96 @@ const utils = require('./utils');
 		t.Errorf("Expected to find utils in imports")
 	}
 }
+
+// writeGeneratedTree writes a project of count TypeScript modules where module i imports modules
+// 2i+1 and 2i+2, so every module is reachable from mod0, spread over directories of 100
+func writeGeneratedTree(dir string, count int) error {
+	if err := os.WriteFile(filepath.Join(dir, "package.json"), []byte(`{"name": "generated"}`), 0644); err != nil {
+		return err
+	}
+	for i := 0; i < count; i++ {
+		var content strings.Builder
+		content.WriteString("import React from 'react';\nimport { log } from '../../shared/log';\n")
+		for _, child := range []int{2*i + 1, 2*i + 2} {
+			if child < count {
+				fmt.Fprintf(&content, "import { mod%d } from '../dir%d/mod%d';\n", child, child/100, child)
+			}
+		}
+		fmt.Fprintf(&content, "\nexport const mod%d = () => log(%d);\n", i, i)
+
+		path := filepath.Join(dir, "src", fmt.Sprintf("dir%d", i/100), fmt.Sprintf("mod%d.ts", i))
+		if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+			return err
+		}
+		if err := os.WriteFile(path, []byte(content.String()), 0644); err != nil {
+			return err
+		}
+	}
+
+	sharedDir := filepath.Join(dir, "shared")
+	if err := os.MkdirAll(sharedDir, 0755); err != nil {
+		return err
+	}
+	return os.WriteFile(filepath.Join(sharedDir, "log.ts"), []byte("export const log = (n: number) => n;\n"), 0644)
+}
+
+// TestProcessFileDeterministic tests that the number of workers doesn't change what's collected
+func TestProcessFileDeterministic(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "deterministic-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer func(original int) { workerCount = original }(workerCount)
+
+	if err := writeGeneratedTree(tempDir, 500); err != nil {
+		t.Fatalf("Failed to generate project: %v", err)
+	}
+	entry := filepath.Join(tempDir, "src", "dir0", "mod0.ts")
+
+	var outputs []string
+	for _, workers := range []int{1, 4, 16} {
+		workerCount = workers
+		resetCollection()
+		results := make(map[string]string)
+		if err := ProcessFile(entry, tempDir, results); err != nil {
+			t.Fatalf("ProcessFile with %d workers failed: %v", workers, err)
+		}
+		if len(results) != 501 {
+			t.Errorf("Expected 501 files with %d workers, got %d", workers, len(results))
+		}
+		outputs = append(outputs, FormatResults(results))
+	}
+
+	for i := 1; i < len(outputs); i++ {
+		if outputs[i] != outputs[0] {
+			t.Errorf("Expected the same output regardless of the number of workers")
+		}
+	}
+}
+
+// BenchmarkProcessFile collects a generated project of 10,000 files
+func BenchmarkProcessFile(b *testing.B) {
+	tempDir, err := os.MkdirTemp("", "benchmark")
+	if err != nil {
+		b.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	if err := writeGeneratedTree(tempDir, 10000); err != nil {
+		b.Fatalf("Failed to generate project: %v", err)
+	}
+	entry := filepath.Join(tempDir, "src", "dir0", "mod0.ts")
+
+	b.ResetTimer()
+	for i := 0; i < b.N; i++ {
+		resetCollection()
+		results := make(map[string]string)
+		if err := ProcessFile(entry, tempDir, results); err != nil {
+			b.Fatalf("ProcessFile failed: %v", err)
+		}
+		if len(results) != 10001 {
+			b.Fatalf("Expected 10001 files, got %d", len(results))
+		}
+	}
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matches import statements, including public and weak imports
+var protoImportPattern = regexp.MustCompile(`(?m)^\s*import\s+(?:public\s+|weak\s+)?["']([^"']+)["']\s*;`)
+
+// Matches comments, so commented out imports are ignored
+var protoCommentPattern = regexp.MustCompile(`(?s:/\*.*?\*/)|//[^\n]*`)
+
+// Matches a module path in a buf.yaml v2 modules list, e.g. "- path: proto"
+var bufModulePathPattern = regexp.MustCompile(`^\s*(?:-\s*)?path:\s*['"]?([^'"\s#]+)`)
+
+// Import roots per project root
+var protoRoots = make(map[string][]string)
+
+// protoLanguage resolves protobuf imports against the configured proto paths and buf module roots
+type protoLanguage struct{}
+
+func init() {
+	RegisterLanguage(protoLanguage{})
+}
+
+// Name identifies the language
+func (protoLanguage) Name() string {
+	return "Protocol Buffers"
+}
+
+// Detect reports whether a file is a protobuf definition
+func (protoLanguage) Detect(fileExt string) bool {
+	return fileExt == ".proto"
+}
+
+// ExtractSpecifiers returns the imported files
+func (protoLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+	code := protoCommentPattern.ReplaceAll(file.Content, nil)
+	for _, match := range protoImportPattern.FindAllSubmatch(code, -1) {
+		specs = append(specs, Specifier{Path: string(match[1])})
+	}
+	return specs
+}
+
+// IsBuiltin reports whether an import is one of the well-known types shipped with protoc
+func (protoLanguage) IsBuiltin(spec Specifier) bool {
+	return strings.HasPrefix(spec.Path, "google/protobuf/")
+}
+
+// ResolveSpecifier looks for an import below each import root, like protoc's -I
+func (protoLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	for _, root := range protoRootsFor(file.ProjectRoot) {
+		candidate := filepath.Join(root, filepath.FromSlash(spec.Path))
+		file.Searched(candidate)
+		if info, err := statFile(candidate); err == nil && !info.IsDir() {
+			return []string{candidate}
+		}
+	}
+	return nil
+}
+
+// protoRootsFor returns the directories imports are relative to: protoPaths from the config, the
+// module roots of buf.work.yaml and buf.yaml, and finally the project root
+func protoRootsFor(projectRoot string) []string {
+	if roots, ok := cacheLoad(protoRoots, projectRoot); ok {
+		return roots
+	}
+
+	var roots []string
+	add := func(dir string) {
+		if path := filepath.Join(projectRoot, dir); !containsString(roots, path) {
+			roots = append(roots, path)
+		}
+	}
+
+	for _, dir := range config.ProtoPaths {
+		add(dir)
+	}
+	if content, err := readFile(filepath.Join(projectRoot, "buf.work.yaml")); err == nil {
+		for _, dir := range yamlSectionItems(string(content), "directories") {
+			add(dir)
+		}
+	}
+	if content, err := readFile(filepath.Join(projectRoot, "buf.yaml")); err == nil {
+		for _, dir := range bufModuleRoots(string(content)) {
+			add(dir)
+		}
+	}
+	add(".")
+
+	cacheStore(protoRoots, projectRoot, roots)
+	return roots
+}
+
+// bufModuleRoots returns the import roots declared by a buf.yaml: the paths of v2 modules, v1
+// build roots, or the directory of the buf.yaml itself
+func bufModuleRoots(content string) []string {
+	var roots []string
+	for _, line := range yamlSection(content, "modules") {
+		if match := bufModulePathPattern.FindStringSubmatch(line); match != nil {
+			roots = append(roots, match[1])
+		}
+	}
+
+	inRoots := false
+	for _, line := range yamlSection(content, "build") {
+		trimmed := strings.TrimSpace(line)
+		if strings.HasSuffix(trimmed, ":") {
+			inRoots = trimmed == "roots:"
+			continue
+		}
+		if match := yamlListItemPattern.FindStringSubmatch(line); inRoots && match != nil {
+			roots = append(roots, match[1])
+		}
+	}
+
+	if len(roots) == 0 {
+		roots = append(roots, ".")
+	}
+	return roots
+}
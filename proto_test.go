@@ -0,0 +1,84 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestProtoImports tests resolving protobuf imports through proto paths and buf configuration
+func TestProtoImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "proto-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"buf.work.yaml": "version: v1\ndirectories:\n  - proto\n  - third_party/protos\n",
+		"proto/acme/billing/v1/invoice.proto": "syntax = \"proto3\";\n\n" +
+			"import \"google/protobuf/timestamp.proto\";\n" +
+			"import public \"acme/common/v1/money.proto\";\n" +
+			"import \"validate/validate.proto\";\n" +
+			"// import \"acme/old.proto\";\n" +
+			"import \"internal/audit.proto\";\n",
+		"proto/acme/common/v1/money.proto":           "syntax = \"proto3\";",
+		"proto/acme/old.proto":                       "syntax = \"proto3\";",
+		"third_party/protos/validate/validate.proto": "syntax = \"proto2\";",
+		"schemas/internal/audit.proto":               "syntax = \"proto3\";",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	config = Config{ProtoPaths: []string{"schemas"}}
+	defer func() { config = Config{} }()
+	protoRoots = make(map[string][]string)
+
+	invoicePath := filepath.Join(tempDir, "proto/acme/billing/v1/invoice.proto")
+	invoice, _ := os.ReadFile(invoicePath)
+	imports := extractImportsFromContent(invoicePath, ".proto", invoice, tempDir)
+
+	expected := []string{
+		filepath.Join(tempDir, "proto/acme/common/v1/money.proto"),
+		filepath.Join(tempDir, "third_party/protos/validate/validate.proto"),
+		filepath.Join(tempDir, "schemas/internal/audit.proto"),
+	}
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for i, path := range expected {
+		if imports[i] != path {
+			t.Errorf("Import %d: got %s, want %s", i, imports[i], path)
+		}
+	}
+}
+
+// TestBufModuleRoots tests reading import roots from buf.yaml files
+func TestBufModuleRoots(t *testing.T) {
+	testCases := map[string][]string{
+		"version: v2\nmodules:\n  - path: proto\n    name: buf.build/acme/api\n  - path: vendor/protos\n": {"proto", "vendor/protos"},
+		"version: v1\nbuild:\n  roots:\n    - src/proto\n  excludes:\n    - src/proto/tmp\n":              {"src/proto"},
+		"version: v1\nlint:\n  use:\n    - DEFAULT\n":                                                     {"."},
+	}
+
+	for content, expected := range testCases {
+		roots := bufModuleRoots(content)
+		if len(roots) != len(expected) {
+			t.Errorf("Expected roots %v, got %v", expected, roots)
+			continue
+		}
+		for i := range expected {
+			if roots[i] != expected[i] {
+				t.Errorf("Expected roots %v, got %v", expected, roots)
+			}
+		}
+	}
+}
@@ -0,0 +1,184 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matches "import a.b, c as d" statements
+var pythonImportPattern = regexp.MustCompile(`(?m)^[ \t]*import[ \t]+([\w. \t,]+)`)
+
+// Matches "from .a.b import c, d" statements, including parenthesized name lists
+var pythonFromPattern = regexp.MustCompile(`(?m)^[ \t]*from[ \t]+(\.*[\w.]*)[ \t]+import[ \t]+(\([^)]*\)|[^\n#]+)`)
+
+// Site-packages directories per project root
+var pythonSitePackages = make(map[string][]string)
+
+// Modules compiled into the interpreter, which can't be shadowed by project files
+var pythonBuiltinModules = []string{"sys", "builtins", "marshal", "__future__", "_imp", "_io", "_thread", "_warnings", "_weakref"}
+
+// pythonLanguage resolves imported modules to files in the project, or in site-packages
+// for third-party packages that may be followed
+type pythonLanguage struct{}
+
+func init() {
+	RegisterLanguage(pythonLanguage{})
+}
+
+// Name identifies the language
+func (pythonLanguage) Name() string {
+	return "Python"
+}
+
+// Detect reports whether a file is Python source
+func (pythonLanguage) Detect(fileExt string) bool {
+	return fileExt == ".py"
+}
+
+// ExtractSpecifiers returns the imported modules. Names imported with from ... import may
+// themselves be submodules of a package, so they're returned as "submodule" specifiers.
+func (pythonLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+
+	for _, match := range pythonImportPattern.FindAllSubmatch(file.Content, -1) {
+		for _, name := range strings.Split(string(match[1]), ",") {
+			if module := strings.Fields(name); len(module) > 0 {
+				specs = append(specs, Specifier{Path: module[0], Kind: "import"})
+			}
+		}
+	}
+
+	for _, match := range pythonFromPattern.FindAllSubmatch(file.Content, -1) {
+		module := string(match[1])
+		if strings.TrimLeft(module, ".") != "" {
+			specs = append(specs, Specifier{Path: module, Kind: "from"})
+		}
+
+		for _, name := range strings.Split(strings.Trim(string(match[2]), "()"), ",") {
+			fields := strings.Fields(name)
+			if len(fields) == 0 || fields[0] == "*" {
+				continue
+			}
+			path := module + "." + fields[0]
+			if strings.TrimLeft(module, ".") == "" {
+				path = module + fields[0]
+			}
+			specs = append(specs, Specifier{Path: path, Kind: "submodule"})
+		}
+	}
+
+	return specs
+}
+
+// IsBuiltin reports whether a module is compiled into the interpreter
+func (pythonLanguage) IsBuiltin(spec Specifier) bool {
+	return containsString(pythonBuiltinModules, strings.SplitN(spec.Path, ".", 2)[0])
+}
+
+// ResolveSpecifier finds the file of an imported module or submodule
+func (pythonLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	fileDir := filepath.Dir(file.Path)
+
+	if spec.Kind != "submodule" {
+		return []string{resolvePythonImport(file, spec.Path)}
+	}
+
+	// A submodule is looked up inside its parent package, if the parent is a package
+	dot := strings.LastIndex(spec.Path, ".")
+	parent, name := spec.Path[:dot], spec.Path[dot+1:]
+	if parent == "" || strings.TrimLeft(parent, ".") == "" {
+		return []string{findPythonModule(file, pythonRelativeBase(parent+".", fileDir), name)}
+	}
+	if resolved := resolvePythonImport(file, parent); filepath.Base(resolved) == "__init__.py" {
+		return []string{findPythonModule(file, filepath.Dir(resolved), name)}
+	}
+	return nil
+}
+
+// resolvePythonImport resolves a module that may be relative to the importing file's package
+func resolvePythonImport(file *SourceFile, module string) string {
+	fileDir := filepath.Dir(file.Path)
+	if !strings.HasPrefix(module, ".") {
+		return resolvePythonModule(file, module)
+	}
+	dots := len(module) - len(strings.TrimLeft(module, "."))
+	return findPythonModule(file, pythonRelativeBase(module[:dots], fileDir), module[dots:])
+}
+
+// pythonRelativeBase returns the directory a relative import starts from: the file's package,
+// going up one level per extra dot
+func pythonRelativeBase(dots string, fileDir string) string {
+	baseDir := fileDir
+	for i := 1; i < len(dots); i++ {
+		baseDir = filepath.Dir(baseDir)
+	}
+	return baseDir
+}
+
+// resolvePythonModule finds an absolutely imported module next to the file, in the project or in site-packages
+func resolvePythonModule(file *SourceFile, module string) string {
+	projectRoot := file.ProjectRoot
+	// Running a script puts its directory on sys.path; projects also commonly use a src/ layout
+	for _, root := range []string{filepath.Dir(file.Path), projectRoot, filepath.Join(projectRoot, "src")} {
+		if path := findPythonModule(file, root, module); path != "" {
+			return path
+		}
+	}
+
+	topLevel := strings.SplitN(module, ".", 2)[0]
+	if !externalAllowed(topLevel) {
+		return ""
+	}
+	for _, sitePackages := range sitePackagesFor(projectRoot) {
+		if path := findPythonModule(file, sitePackages, module); path != "" {
+			return path
+		}
+	}
+	return ""
+}
+
+// findPythonModule maps a dotted module name below root to its .py file or package __init__.py,
+// recording the search in file
+func findPythonModule(file *SourceFile, root string, module string) string {
+	base := filepath.Join(root, filepath.FromSlash(strings.ReplaceAll(module, ".", "/")))
+	file.Searched(base)
+	for _, candidate := range []string{base + ".py", filepath.Join(base, "__init__.py")} {
+		if info, err := statFile(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
+
+// sitePackagesFor returns the site-packages directories of the active or project-local virtualenvs
+func sitePackagesFor(projectRoot string) []string {
+	if dirs, ok := cacheLoad(pythonSitePackages, projectRoot); ok {
+		return dirs
+	}
+
+	envs := []string{
+		filepath.Join(projectRoot, ".venv"),
+		filepath.Join(projectRoot, "venv"),
+		filepath.Join(projectRoot, "env"),
+	}
+	if virtualEnv := os.Getenv("VIRTUAL_ENV"); virtualEnv != "" {
+		envs = append([]string{virtualEnv}, envs...)
+	}
+
+	var dirs []string
+	for _, env := range envs {
+		matches, _ := globFiles(filepath.Join(env, "lib", "python*", "site-packages"))
+		dirs = append(dirs, matches...)
+		// Windows virtualenvs don't include the Python version
+		if windowsDir := filepath.Join(env, "Lib", "site-packages"); !containsString(dirs, windowsDir) {
+			if _, err := statFile(windowsDir); err == nil {
+				dirs = append(dirs, windowsDir)
+			}
+		}
+	}
+
+	cacheStore(pythonSitePackages, projectRoot, dirs)
+	return dirs
+}
@@ -0,0 +1,63 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtractPythonImports tests absolute, relative and submodule imports
+func TestExtractPythonImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "python-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := []string{
+		"app/__init__.py",
+		"app/models/__init__.py",
+		"app/models/user.py",
+		"app/services/__init__.py",
+		"app/services/billing.py",
+		"app/services/helpers.py",
+		"app/config.py",
+	}
+	for _, path := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(""), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	billingPath := filepath.Join(tempDir, "app", "services", "billing.py")
+	billing := []byte(`
+import os, json
+import app.config as cfg
+from app.models import user
+from . import helpers
+from ..models.user import User
+from requests import Session
+`)
+
+	config = Config{}
+	imports := extractImportsFromContent(billingPath, ".py", billing, tempDir)
+	expected := []string{
+		"app/config.py",
+		"app/models/__init__.py",
+		"app/models/user.py",
+		"app/services/helpers.py",
+	}
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for _, path := range expected {
+		if !containsString(imports, filepath.Join(tempDir, path)) {
+			t.Errorf("Expected to find %s in imports", path)
+		}
+	}
+}
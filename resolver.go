@@ -1,7 +1,6 @@
 package main
 
 import (
-	"os"
 	"path/filepath"
 	"strings"
 )
@@ -16,14 +15,14 @@ func ResolveImportPath(importPath string, currentDir, projectRoot string) (strin
 
 	// If the resolved path already has an extension, check if it exists directly
 	if filepath.Ext(resolvedPath) != "" {
-		if _, err := os.Stat(resolvedPath); err == nil {
+		if _, err := statFile(resolvedPath); err == nil {
 			return resolvedPath, nil
 		}
 	} else {
 		// Try with each supported extension
-		for ext := range supportedExtensions {
+		for _, ext := range candidateExtensions() {
 			testPath := resolvedPath + ext
-			if _, err := os.Stat(testPath); err == nil {
+			if _, err := statFile(testPath); err == nil {
 				return testPath, nil
 			}
 		}
@@ -33,7 +32,7 @@ func ResolveImportPath(importPath string, currentDir, projectRoot string) (strin
 		for _, ext := range jsExtensions {
 			if _, ok := supportedExtensions[ext]; ok {
 				indexFile := filepath.Join(resolvedPath, "index"+ext)
-				if _, err := os.Stat(indexFile); err == nil {
+				if _, err := statFile(indexFile); err == nil {
 					return indexFile, nil
 				}
 			}
@@ -66,14 +65,14 @@ func resolveImportByType(importPath string, currentDir, projectRoot string) (str
 		srcPath := filepath.Join(projectRoot, "src", strings.TrimPrefix(importPath, "@/"))
 
 		// Try with src directory first
-		if _, err := os.Stat(srcPath); err == nil {
+		if _, err := statFile(srcPath); err == nil {
 			return srcPath, nil
 		}
 
 		// If file not found in src, try adding extension
-		for ext := range supportedExtensions {
+		for _, ext := range candidateExtensions() {
 			testPath := srcPath + ext
-			if _, err := os.Stat(testPath); err == nil {
+			if _, err := statFile(testPath); err == nil {
 				return testPath, nil
 			}
 		}
@@ -90,7 +89,7 @@ func resolveImportByType(importPath string, currentDir, projectRoot string) (str
 	}
 
 	for _, path := range possiblePaths {
-		if _, err := os.Stat(path); err == nil {
+		if _, err := statFile(path); err == nil {
 			return path, nil
 		}
 	}
@@ -108,6 +107,7 @@ func FindProjectRoot(startPath string) (string, error) {
 		".git",
 		"requirements.txt",
 		"setup.py",
+		"Gemfile",
 		"docker-compose.yml",
 		"Makefile",
 	}
@@ -115,7 +115,7 @@ func FindProjectRoot(startPath string) (string, error) {
 	dir := startPath
 	for {
 		for _, indicator := range indicators {
-			if _, err := os.Stat(filepath.Join(dir, indicator)); err == nil {
+			if _, err := statFile(filepath.Join(dir, indicator)); err == nil {
 				return dir, nil
 			}
 		}
@@ -132,3 +132,13 @@ func FindProjectRoot(startPath string) (string, error) {
 	// If we couldn't find a project root, use the directory of the initial file
 	return filepath.Dir(startPath), nil
 }
+
+// containsString reports whether a slice contains the given string
+func containsString(list []string, s string) bool {
+	for _, item := range list {
+		if item == s {
+			return true
+		}
+	}
+	return false
+}
@@ -0,0 +1,273 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+	"unicode"
+)
+
+// Matches require, require_relative, load and autoload calls along with their target
+var rubyRequirePattern = regexp.MustCompile(`(?m)^\s*(require_relative|require|load|autoload)\b\s*\(?\s*(?::\w+\s*,\s*)?['"]([^'"]+)['"]`)
+
+// Matches require_paths assignments in a gemspec, either as an array literal or %w list
+var gemspecRequirePathsPattern = regexp.MustCompile(`require_paths\s*=\s*(?:\[([^\]]*)\]|%w[\[\(\{]([^\]\)\}]*)[\]\)\}])`)
+
+// Matches constant references such as UsersController or Billing::Invoice
+var rubyConstantPattern = regexp.MustCompile(`(?:^|[^\w:@$])((?:::)?[A-Z]\w*(?:::[A-Z]\w*)*)`)
+
+// Matches string literals and comments so constants inside them are ignored
+var rubyNoisePattern = regexp.MustCompile(`"(?:\\.|[^"\\])*"|'(?:\\.|[^'\\])*'|(?m:#.*$)`)
+
+// Matches the quoted entries of a gemspec array literal
+var rubyQuotedPattern = regexp.MustCompile(`['"]([^'"]+)['"]`)
+
+// Cache of load paths per project root
+var rubyLoadPaths = make(map[string][]string)
+
+// rubyLanguage resolves requires against load paths and, in Rails mode, constant references
+// against Zeitwerk autoload roots
+type rubyLanguage struct{}
+
+func init() {
+	RegisterLanguage(rubyLanguage{})
+}
+
+// Name identifies the language
+func (rubyLanguage) Name() string {
+	return "Ruby"
+}
+
+// Detect reports whether a file is Ruby source
+func (rubyLanguage) Detect(fileExt string) bool {
+	return fileExt == ".rb"
+}
+
+// ExtractSpecifiers returns the targets of require-like calls, with the call as their kind, and in
+// Rails mode the constants the file references
+func (rubyLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+	for _, match := range rubyRequirePattern.FindAllSubmatch(file.Content, -1) {
+		specs = append(specs, Specifier{Path: string(match[2]), Kind: string(match[1])})
+	}
+
+	if config.Rails {
+		seen := make(map[string]struct{})
+		code := rubyNoisePattern.ReplaceAll(file.Content, nil)
+		for _, match := range rubyConstantPattern.FindAllSubmatch(code, -1) {
+			constant := string(match[1])
+			if _, ok := seen[constant]; !ok {
+				seen[constant] = struct{}{}
+				specs = append(specs, Specifier{Path: constant, Kind: "constant"})
+			}
+		}
+	}
+
+	return specs
+}
+
+// IsBuiltin is always false; gems and the standard library simply aren't found on the load paths
+func (rubyLanguage) IsBuiltin(spec Specifier) bool {
+	return false
+}
+
+// ResolveSpecifier finds the file a require or constant refers to
+func (rubyLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	fileDir := filepath.Dir(file.Path)
+	target := spec.Path
+	find := func(path string) string {
+		file.Searched(path)
+		return findRubyFile(path)
+	}
+
+	var resolved string
+	switch spec.Kind {
+	case "constant":
+		resolved = resolveRailsConstant(target, file.Path, file.ProjectRoot)
+	case "require_relative":
+		resolved = find(filepath.Join(fileDir, target))
+	case "load":
+		// load paths are relative to the working directory, so try next to the file and then the project root
+		resolved = find(filepath.Join(fileDir, target))
+		if resolved == "" {
+			resolved = find(filepath.Join(file.ProjectRoot, target))
+		}
+	default:
+		if strings.HasPrefix(target, ".") {
+			resolved = find(filepath.Join(fileDir, target))
+			break
+		}
+		for _, loadPath := range rubyLoadPathsFor(file.ProjectRoot) {
+			if resolved = find(filepath.Join(loadPath, target)); resolved != "" {
+				break
+			}
+		}
+	}
+
+	// Anything not found on the load paths is a gem or part of the standard library
+	return []string{resolved}
+}
+
+// findRubyFile returns the path with a .rb extension added if needed, or "" if it doesn't exist
+func findRubyFile(path string) string {
+	if filepath.Ext(path) != ".rb" {
+		if _, err := statFile(path + ".rb"); err == nil {
+			return path + ".rb"
+		}
+	}
+	if info, err := statFile(path); err == nil && !info.IsDir() {
+		return path
+	}
+	return ""
+}
+
+// rubyLoadPathsFor returns lib/ plus any require_paths declared in the project's gemspecs
+func rubyLoadPathsFor(projectRoot string) []string {
+	if paths, ok := cacheLoad(rubyLoadPaths, projectRoot); ok {
+		return paths
+	}
+
+	paths := []string{filepath.Join(projectRoot, "lib")}
+	gemspecs, _ := globFiles(filepath.Join(projectRoot, "*.gemspec"))
+	for _, gemspec := range gemspecs {
+		content, err := readFile(gemspec)
+		if err != nil {
+			continue
+		}
+		for _, match := range gemspecRequirePathsPattern.FindAllSubmatch(content, -1) {
+			var entries []string
+			if len(match[1]) > 0 {
+				for _, quoted := range rubyQuotedPattern.FindAllSubmatch(match[1], -1) {
+					entries = append(entries, string(quoted[1]))
+				}
+			} else {
+				entries = strings.Fields(string(match[2]))
+			}
+			for _, entry := range entries {
+				path := filepath.Join(projectRoot, entry)
+				if !containsString(paths, path) {
+					paths = append(paths, path)
+				}
+			}
+		}
+	}
+
+	cacheStore(rubyLoadPaths, projectRoot, paths)
+	return paths
+}
+
+// resolveRailsConstant maps a constant reference to its file under the Zeitwerk autoload roots
+func resolveRailsConstant(constant string, filePath string, projectRoot string) string {
+	roots := railsAutoloadRoots(projectRoot)
+	namespace := railsNamespaceOf(filePath, roots)
+
+	// Like Ruby's lexical lookup, try the enclosing namespaces before the top level
+	var candidates []string
+	if !strings.HasPrefix(constant, "::") {
+		for i := len(namespace); i > 0; i-- {
+			candidates = append(candidates, strings.Join(append(namespace[:i:i], constant), "::"))
+		}
+	}
+	candidates = append(candidates, strings.TrimPrefix(constant, "::"))
+
+	for _, candidate := range candidates {
+		if resolved := findRailsConstant(candidate, roots); resolved != "" {
+			return resolved
+		}
+	}
+	return ""
+}
+
+// findRailsConstant looks for the file defining a constant, e.g. Billing::Invoice in billing/invoice.rb
+func findRailsConstant(constant string, roots []string) string {
+	var parts []string
+	for _, part := range strings.Split(constant, "::") {
+		parts = append(parts, underscore(part))
+	}
+	relPath := filepath.Join(parts...) + ".rb"
+
+	for _, root := range roots {
+		path := filepath.Join(root, relPath)
+		if _, err := statFile(path); err == nil {
+			return path
+		}
+	}
+	return ""
+}
+
+// railsAutoloadRoots returns the directories Zeitwerk autoloads from: app/*, app/*/concerns and lib
+func railsAutoloadRoots(projectRoot string) []string {
+	var roots []string
+
+	appDirs, _ := globFiles(filepath.Join(projectRoot, "app", "*"))
+	for _, dir := range appDirs {
+		if info, err := statFile(dir); err == nil && info.IsDir() {
+			roots = append(roots, dir)
+			concerns := filepath.Join(dir, "concerns")
+			if _, err := statFile(concerns); err == nil {
+				roots = append(roots, concerns)
+			}
+		}
+	}
+
+	return append(roots, filepath.Join(projectRoot, "lib"))
+}
+
+// railsNamespaceOf derives the namespace of a file from its path below an autoload root
+func railsNamespaceOf(filePath string, roots []string) []string {
+	// Prefer the most specific root so app/models/concerns isn't treated as a namespace
+	best := ""
+	for _, root := range roots {
+		if strings.HasPrefix(filePath, root+string(filepath.Separator)) && len(root) > len(best) {
+			best = root
+		}
+	}
+	if best == "" {
+		return nil
+	}
+
+	rel, err := filepath.Rel(best, filepath.Dir(filePath))
+	if err != nil || rel == "." {
+		return nil
+	}
+
+	var namespace []string
+	for _, dir := range strings.Split(rel, string(filepath.Separator)) {
+		namespace = append(namespace, camelize(dir))
+	}
+	return namespace
+}
+
+// underscore converts a constant name to its file name, e.g. HTMLParser to html_parser
+func underscore(name string) string {
+	var builder strings.Builder
+	runes := []rune(name)
+
+	for i, r := range runes {
+		if unicode.IsUpper(r) {
+			if i > 0 && (unicode.IsLower(runes[i-1]) || unicode.IsDigit(runes[i-1]) ||
+				(unicode.IsUpper(runes[i-1]) && i+1 < len(runes) && unicode.IsLower(runes[i+1]))) {
+				builder.WriteByte('_')
+			}
+			builder.WriteRune(unicode.ToLower(r))
+		} else {
+			builder.WriteRune(r)
+		}
+	}
+
+	return builder.String()
+}
+
+// camelize converts a file name to its constant name, e.g. users_controller to UsersController
+func camelize(name string) string {
+	var builder strings.Builder
+	for _, part := range strings.Split(name, "_") {
+		if part == "" {
+			continue
+		}
+		runes := []rune(part)
+		builder.WriteRune(unicode.ToUpper(runes[0]))
+		builder.WriteString(string(runes[1:]))
+	}
+	return builder.String()
+}
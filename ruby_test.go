@@ -0,0 +1,109 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtractRubyImports tests load path, require_relative and Rails constant resolution
+func TestExtractRubyImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "ruby-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	// Create a Rails-like project with a gemspec that adds ext/ to the load path
+	files := map[string]string{
+		"demo.gemspec":                        `spec.require_paths = ["lib", "ext"]`,
+		"lib/demo/client.rb":                  "module Demo; class Client; end; end",
+		"ext/native.rb":                       "# native bindings",
+		"app/services/helpers.rb":             "# helpers",
+		"app/models/user.rb":                  "class User; end",
+		"app/models/billing/invoice.rb":       "module Billing; class Invoice; end; end",
+		"app/models/concerns/html_parser.rb":  "module HTMLParser; end",
+		"app/controllers/users_controller.rb": "class UsersController; end",
+		"app/services/billing/checkout.rb":    "",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	checkoutPath := filepath.Join(tempDir, "app", "services", "billing", "checkout.rb")
+	checkout := []byte(`
+require 'json'
+require 'demo/client'
+require 'native'
+require_relative '../helpers'
+
+module Billing
+  class Checkout
+    # UsersController in a comment is ignored
+    def call
+      label = "HTMLParser in a string is ignored"
+      Invoice.new(User.first, HTMLParser)
+    end
+  end
+end
+`)
+
+	// Without Rails mode only requires are followed
+	config = Config{}
+	imports := extractImportsFromContent(checkoutPath, ".rb", checkout, tempDir)
+	expected := []string{
+		filepath.Join(tempDir, "lib", "demo", "client.rb"),
+		filepath.Join(tempDir, "ext", "native.rb"),
+		filepath.Join(tempDir, "app", "services", "helpers.rb"),
+	}
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for _, path := range expected {
+		if !containsString(imports, path) {
+			t.Errorf("Expected to find %s in imports", path)
+		}
+	}
+
+	// Rails mode also maps constant references to autoloaded files
+	config = Config{Rails: true}
+	defer func() { config = Config{} }()
+	imports = extractImportsFromContent(checkoutPath, ".rb", checkout, tempDir)
+	expected = append(expected,
+		filepath.Join(tempDir, "app", "models", "billing", "invoice.rb"),
+		filepath.Join(tempDir, "app", "models", "user.rb"),
+		filepath.Join(tempDir, "app", "models", "concerns", "html_parser.rb"),
+	)
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for _, path := range expected {
+		if !containsString(imports, path) {
+			t.Errorf("Expected to find %s in imports", path)
+		}
+	}
+}
+
+// TestUnderscore tests the Zeitwerk constant to file name conversion
+func TestUnderscore(t *testing.T) {
+	testCases := map[string]string{
+		"User":            "user",
+		"UsersController": "users_controller",
+		"HTMLParser":      "html_parser",
+		"OAuth2Client":    "o_auth2_client",
+		"V1":              "v1",
+	}
+
+	for constant, expected := range testCases {
+		if got := underscore(constant); got != expected {
+			t.Errorf("underscore(%q) = %q, want %q", constant, got, expected)
+		}
+	}
+}
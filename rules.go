@@ -0,0 +1,176 @@
+package main
+
+import (
+	"fmt"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// LanguageRule declares how to follow imports in a file type fixfiles has no built-in support for
+type LanguageRule struct {
+	Name string `json:"name"`
+	// Extensions claimed by the rule, e.g. [".j2", ".jinja"]
+	Extensions []string `json:"extensions"`
+	// Patterns are regular expressions whose first capture group is the imported path
+	Patterns []string `json:"patterns"`
+	// Resolve is where imported paths are looked up: "relative" to the importing file (the default),
+	// "root" for the project root, or "search" for the importing file's directory and then SearchPaths
+	Resolve string `json:"resolve"`
+	// SearchPaths are directories, relative to the project root, searched with the "search" strategy
+	SearchPaths []string `json:"searchPaths"`
+}
+
+// ruleLanguage extracts imports with a rule's patterns and resolves them with its strategy
+type ruleLanguage struct {
+	patternLanguage
+	rule LanguageRule
+}
+
+// ruleRegistration is what registering a rule's extension replaced, so it can be put back
+type ruleRegistration struct {
+	supported bool
+	patterns  []*regexp.Regexp
+	// hadPatterns tells a missing importPatterns entry from an empty one
+	hadPatterns bool
+}
+
+// What the rules registered last replaced, by extension
+var ruleRegistrations = make(map[string]ruleRegistration)
+
+// registerLanguageRules adds the extensions and patterns of each rule to supportedExtensions and
+// importPatterns, and registers a language for them. Rules take precedence over built-in languages.
+// The rules replace any registered before, so the config can be loaded again.
+func registerLanguageRules(rules []LanguageRule) error {
+	var ruleLanguages []ruleLanguage
+	var rulePatterns [][]*regexp.Regexp
+	for i, rule := range rules {
+		if rule.Name == "" {
+			rule.Name = fmt.Sprintf("languages[%d]", i)
+		}
+		if len(rule.Extensions) == 0 {
+			return fmt.Errorf("%s: no extensions", rule.Name)
+		}
+		switch rule.Resolve {
+		case "":
+			rule.Resolve = "relative"
+		case "relative", "root", "search":
+		default:
+			return fmt.Errorf("%s: unknown resolve strategy %q", rule.Name, rule.Resolve)
+		}
+
+		var patterns []*regexp.Regexp
+		for _, expr := range rule.Patterns {
+			pattern, err := regexp.Compile(expr)
+			if err != nil {
+				return fmt.Errorf("%s: invalid pattern %q: %v", rule.Name, expr, err)
+			}
+			if pattern.NumSubexp() < 1 {
+				return fmt.Errorf("%s: pattern %q has no capture group for the imported path", rule.Name, expr)
+			}
+			patterns = append(patterns, pattern)
+		}
+
+		extensions := make([]string, len(rule.Extensions))
+		for j, ext := range rule.Extensions {
+			if !strings.HasPrefix(ext, ".") {
+				ext = "." + ext
+			}
+			extensions[j] = ext
+		}
+		rule.Extensions = extensions
+
+		ruleLanguages = append(ruleLanguages, ruleLanguage{rule: rule})
+		rulePatterns = append(rulePatterns, patterns)
+	}
+
+	// Only valid rules replace the registered ones
+	resetLanguageRules()
+	for i, lang := range ruleLanguages {
+		for _, ext := range lang.rule.Extensions {
+			if _, ok := ruleRegistrations[ext]; !ok {
+				_, supported := supportedExtensions[ext]
+				patterns, hadPatterns := importPatterns[ext]
+				ruleRegistrations[ext] = ruleRegistration{supported: supported, patterns: patterns, hadPatterns: hadPatterns}
+			}
+			supportedExtensions[ext] = struct{}{}
+			importPatterns[ext] = rulePatterns[i]
+		}
+		RegisterLanguage(lang)
+	}
+
+	return nil
+}
+
+// resetLanguageRules unregisters the languages of rules and puts back the extensions and patterns
+// they replaced
+func resetLanguageRules() {
+	var kept []Language
+	for _, lang := range languages {
+		if _, ok := lang.(ruleLanguage); !ok {
+			kept = append(kept, lang)
+		}
+	}
+	languages = kept
+
+	for ext, registration := range ruleRegistrations {
+		if registration.supported {
+			supportedExtensions[ext] = struct{}{}
+		} else {
+			delete(supportedExtensions, ext)
+		}
+		if registration.hadPatterns {
+			importPatterns[ext] = registration.patterns
+		} else {
+			delete(importPatterns, ext)
+		}
+	}
+	ruleRegistrations = make(map[string]ruleRegistration)
+}
+
+// Name identifies the language
+func (l ruleLanguage) Name() string {
+	return l.rule.Name
+}
+
+// Detect reports whether the rule claims an extension
+func (l ruleLanguage) Detect(fileExt string) bool {
+	return containsString(l.rule.Extensions, fileExt)
+}
+
+// ResolveSpecifier looks for the imported path in the rule's directories, trying the rule's
+// extensions when the path has none of its own
+func (l ruleLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	var baseDirs []string
+	switch {
+	case strings.HasPrefix(spec.Path, "/"), l.rule.Resolve == "root":
+		baseDirs = []string{file.ProjectRoot}
+	case l.rule.Resolve == "search":
+		baseDirs = []string{filepath.Dir(file.Path)}
+		for _, dir := range l.rule.SearchPaths {
+			baseDirs = append(baseDirs, filepath.Join(file.ProjectRoot, dir))
+		}
+	default:
+		baseDirs = []string{filepath.Dir(file.Path)}
+	}
+
+	for _, dir := range baseDirs {
+		base := filepath.Join(dir, spec.Path)
+		file.Searched(base)
+		for _, candidate := range append([]string{base}, l.candidates(base)...) {
+			if info, err := statFile(candidate); err == nil && !info.IsDir() {
+				return []string{candidate}
+			}
+		}
+	}
+	return nil
+}
+
+// candidates returns base with each of the rule's extensions added
+func (l ruleLanguage) candidates(base string) []string {
+	var paths []string
+	for _, ext := range l.rule.Extensions {
+		paths = append(paths, base+ext)
+	}
+	return paths
+}
@@ -0,0 +1,151 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestLanguageRules tests following imports in file types declared in the config
+func TestLanguageRules(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "language-rules-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"app/pages/home.j2":         "{% extends \"base.j2\" %}\n{% include 'partials/nav' %}\n",
+		"templates/base.j2":         "<html>{% block body %}{% endblock %}</html>",
+		"templates/partials/nav.j2": "<nav></nav>",
+		"deploy/service.inc.yaml":   "!include ../shared/env.inc\nname: web\n",
+		"shared/env.inc.yaml":       "!include /config/root.inc\n",
+		"config/root.inc.yaml":      "region: eu",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	defer resetLanguageRules()
+
+	rules := []LanguageRule{
+		{
+			Name:        "jinja",
+			Extensions:  []string{".j2"},
+			Patterns:    []string{`{%-?\s*(?:include|import|extends|from)\s+['"]([^'"]+)['"]`},
+			Resolve:     "search",
+			SearchPaths: []string{"templates"},
+		},
+		{
+			Name:       "yaml includes",
+			Extensions: []string{"yaml"},
+			Patterns:   []string{`(?m)^!include\s+(\S+)`},
+		},
+	}
+	if err := registerLanguageRules(rules); err != nil {
+		t.Fatalf("registerLanguageRules failed: %v", err)
+	}
+
+	testCases := []struct {
+		entry    string
+		expected []string
+	}{
+		{
+			entry:    "app/pages/home.j2",
+			expected: []string{"app/pages/home.j2", "templates/base.j2", "templates/partials/nav.j2"},
+		},
+		{
+			// Extensionless paths are tried with the rule's extensions, and /paths start at the project root
+			entry:    "deploy/service.inc.yaml",
+			expected: []string{"deploy/service.inc.yaml", "shared/env.inc.yaml", "config/root.inc.yaml"},
+		},
+	}
+
+	for _, tc := range testCases {
+		processedFiles = make(map[string]struct{})
+		results := make(map[string]string)
+		if err := ProcessFile(filepath.Join(tempDir, tc.entry), tempDir, results); err != nil {
+			t.Fatalf("ProcessFile failed: %v", err)
+		}
+
+		if len(results) != len(tc.expected) {
+			t.Fatalf("Expected %d files for %s, got %d: %v", len(tc.expected), tc.entry, len(results), keys(results))
+		}
+		for _, path := range tc.expected {
+			if _, ok := results[filepath.Join(tempDir, path)]; !ok {
+				t.Errorf("Expected file %s not found in results", path)
+			}
+		}
+	}
+}
+
+// TestLanguageRulesValidation tests rejecting invalid rules
+func TestLanguageRulesValidation(t *testing.T) {
+	defer resetLanguageRules()
+
+	testCases := map[string]LanguageRule{
+		"No extensions":    {Name: "a", Patterns: []string{`include (\S+)`}},
+		"Invalid pattern":  {Name: "b", Extensions: []string{".b"}, Patterns: []string{`include (\S+`}},
+		"No capture group": {Name: "c", Extensions: []string{".c1"}, Patterns: []string{`include \S+`}},
+		"Unknown strategy": {Name: "d", Extensions: []string{".d"}, Resolve: "nearest"},
+	}
+
+	for name, rule := range testCases {
+		if err := registerLanguageRules([]LanguageRule{rule}); err == nil {
+			t.Errorf("%s: expected an error", name)
+		}
+	}
+}
+
+// TestLanguageRulesReload tests that registering rules again replaces the ones registered before
+func TestLanguageRulesReload(t *testing.T) {
+	defer resetLanguageRules()
+
+	builtin := len(languages)
+	cssPatterns := importPatterns[".css"]
+	jinja := LanguageRule{Name: "jinja", Extensions: []string{".j2"}, Patterns: []string{`include '([^']+)'`}}
+	styles := LanguageRule{Name: "styles", Extensions: []string{".css"}, Patterns: []string{`use '([^']+)'`}}
+
+	for i := 0; i < 3; i++ {
+		if err := registerLanguageRules([]LanguageRule{jinja, styles}); err != nil {
+			t.Fatalf("registerLanguageRules failed: %v", err)
+		}
+	}
+	if len(languages) != builtin+2 {
+		t.Errorf("Expected %d languages after registering the same rules again, got %d", builtin+2, len(languages))
+	}
+
+	// A rule removed from the config stops applying
+	if err := registerLanguageRules([]LanguageRule{styles}); err != nil {
+		t.Fatalf("registerLanguageRules failed: %v", err)
+	}
+	if isSupportedExtension(".j2") || languageFor(".j2") != nil {
+		t.Errorf("Expected .j2 not to be supported once its rule is removed")
+	}
+	if lang := languageFor(".css"); lang == nil || lang.Name() != "styles" {
+		t.Errorf("Expected .css to be handled by the styles rule, got %v", lang)
+	}
+
+	// An invalid config leaves the registered rules alone
+	if err := registerLanguageRules([]LanguageRule{jinja, {Name: "bad"}}); err == nil {
+		t.Fatalf("Expected an error for a rule without extensions")
+	}
+	if languageFor(".j2") != nil || languageFor(".css").Name() != "styles" {
+		t.Errorf("Expected an invalid config not to change the registered rules")
+	}
+
+	// Built-in extensions get their patterns back
+	if err := registerLanguageRules(nil); err != nil {
+		t.Fatalf("registerLanguageRules failed: %v", err)
+	}
+	if len(languages) != builtin || !isSupportedExtension(".css") || len(importPatterns[".css"]) != len(cssPatterns) {
+		t.Errorf("Expected the built-in languages and .css patterns back once the rules are removed")
+	}
+}
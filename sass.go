@@ -0,0 +1,168 @@
+package main
+
+import (
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Matches @use, @forward and @import rules up to the end of the statement
+var styleRulePattern = regexp.MustCompile(`(?m)@(use|forward|import)\s+([^;{\n]+)`)
+
+// Matches quoted strings and url() arguments inside a rule
+var styleTargetPattern = regexp.MustCompile(`url\(\s*['"]?([^'")]+)['"]?\s*\)|"([^"]+)"|'([^']+)'`)
+
+// Matches Less import options such as (reference, optional)
+var lessOptionsPattern = regexp.MustCompile(`^\s*\([^)]*\)`)
+
+// Matches block comments and whole-line comments
+var styleCommentPattern = regexp.MustCompile(`(?s:/\*.*?\*/)|(?m:^[ \t]*//.*$)`)
+
+// styleLanguage resolves the @use, @forward and @import rules of Sass, SCSS and Less files
+type styleLanguage struct{}
+
+func init() {
+	RegisterLanguage(styleLanguage{})
+}
+
+// Name identifies the language
+func (styleLanguage) Name() string {
+	return "Sass/Less"
+}
+
+// Detect reports whether a file is a Sass, SCSS or Less stylesheet
+func (styleLanguage) Detect(fileExt string) bool {
+	return fileExt == ".scss" || fileExt == ".sass" || fileExt == ".less"
+}
+
+// ExtractSpecifiers returns the targets of each rule, with the rule as their kind
+func (styleLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+	code := styleCommentPattern.ReplaceAll(file.Content, nil)
+
+	for _, match := range styleRulePattern.FindAllSubmatch(code, -1) {
+		rule, args := string(match[1]), string(match[2])
+		if file.Ext == ".less" {
+			args = lessOptionsPattern.ReplaceAllString(args, "")
+		}
+
+		var targets []string
+		for _, target := range styleTargetPattern.FindAllStringSubmatch(args, -1) {
+			targets = append(targets, target[1]+target[2]+target[3])
+		}
+		// The indented syntax allows unquoted, comma separated @import targets
+		if len(targets) == 0 && file.Ext == ".sass" && rule == "import" {
+			for _, target := range strings.Split(args, ",") {
+				if target = strings.TrimSpace(target); target != "" {
+					targets = append(targets, target)
+				}
+			}
+		}
+		// @use and @forward take a single URL followed by with/as/show/hide clauses
+		if rule != "import" && len(targets) > 1 {
+			targets = targets[:1]
+		}
+
+		for _, target := range targets {
+			if !isExternalURL(target) {
+				specs = append(specs, Specifier{Path: target, Kind: rule})
+			}
+		}
+	}
+
+	return specs
+}
+
+// IsBuiltin reports whether a target is a Sass built-in module such as sass:math
+func (styleLanguage) IsBuiltin(spec Specifier) bool {
+	return strings.HasPrefix(spec.Path, "sass:")
+}
+
+// ResolveSpecifier finds the stylesheet a rule imports
+func (styleLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	for _, base := range styleImportBases(spec.Path, filepath.Dir(file.Path), file.ProjectRoot) {
+		file.Searched(base)
+	}
+	return []string{resolveStyleImport(spec.Path, file.Ext, spec.Kind, filepath.Dir(file.Path), file.ProjectRoot)}
+}
+
+// resolveStyleImport finds the file a style import refers to, or "" if it's missing or third-party
+func resolveStyleImport(target string, fileExt string, rule string, fileDir string, projectRoot string) string {
+	// ~ and pkg: refer to node_modules, which only counts when it links back into the project
+	if strings.HasPrefix(target, "~") || strings.HasPrefix(target, "pkg:") {
+		target = strings.TrimPrefix(strings.TrimPrefix(target, "~"), "pkg:")
+		resolved := findStyleFile(filepath.Join(projectRoot, "node_modules", target), fileExt, rule)
+		if resolved == "" {
+			return ""
+		}
+		resolvedPath, err := realPath(resolved)
+		if err != nil || strings.Contains(resolvedPath, string(filepath.Separator)+"node_modules"+string(filepath.Separator)) {
+			return ""
+		}
+		return resolvedPath
+	}
+
+	for _, base := range styleImportBases(target, fileDir, projectRoot) {
+		if resolved := findStyleFile(base, fileExt, rule); resolved != "" {
+			return resolved
+		}
+	}
+
+	return ""
+}
+
+// styleImportBases returns the paths a style import is looked for at, before applying the rules
+// for partials and index files: relative to the importing file first, then each load path
+func styleImportBases(target string, fileDir string, projectRoot string) []string {
+	if strings.HasPrefix(target, "~") || strings.HasPrefix(target, "pkg:") {
+		return nil
+	}
+	if strings.HasPrefix(target, "/") {
+		return []string{filepath.Join(projectRoot, target)}
+	}
+	bases := []string{filepath.Join(fileDir, target)}
+	for _, loadPath := range config.StyleLoadPaths {
+		bases = append(bases, filepath.Join(projectRoot, loadPath, target))
+	}
+	return bases
+}
+
+// findStyleFile applies the Sass or Less rules for turning an import URL into a file
+func findStyleFile(base string, fileExt string, rule string) string {
+	var candidates []string
+
+	if fileExt == ".less" {
+		candidates = append(candidates, base)
+		if filepath.Ext(base) == "" {
+			candidates = append(candidates, base+".less")
+		}
+	} else {
+		dir, name := filepath.Dir(base), filepath.Base(base)
+		ext := filepath.Ext(name)
+
+		if ext == ".scss" || ext == ".sass" || ext == ".css" {
+			candidates = append(candidates, base, filepath.Join(dir, "_"+name))
+		} else {
+			// Import-only files take precedence for @import
+			if rule == "import" {
+				for _, e := range []string{".scss", ".sass"} {
+					candidates = append(candidates, base+".import"+e, filepath.Join(dir, "_"+name+".import"+e))
+				}
+			}
+			// Then the file itself and its partial, then an index file in a directory of that name
+			for _, e := range []string{".scss", ".sass", ".css"} {
+				candidates = append(candidates, base+e, filepath.Join(dir, "_"+name+e))
+			}
+			for _, e := range []string{".scss", ".sass", ".css"} {
+				candidates = append(candidates, filepath.Join(base, "_index"+e), filepath.Join(base, "index"+e))
+			}
+		}
+	}
+
+	for _, candidate := range candidates {
+		if info, err := statFile(candidate); err == nil && !info.IsDir() {
+			return candidate
+		}
+	}
+	return ""
+}
@@ -0,0 +1,91 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtractStyleImports tests Sass partial, index, load path and node_modules resolution
+func TestExtractStyleImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "style-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := []string{
+		"styles/_variables.scss",
+		"styles/theme/_index.scss",
+		"styles/_mixins.scss",
+		"styles/_a.scss",
+		"styles/b.css",
+		"styles/_commented.scss",
+		"shared/_tokens.scss",
+		"node_modules/bootstrap/scss/_functions.scss",
+		"packages/styles/_base.scss",
+		"less/mixins.less",
+	}
+	for _, path := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(""), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	// Workspace packages are linked into node_modules and count as project code
+	if err := os.MkdirAll(filepath.Join(tempDir, "node_modules", "@acme"), 0755); err != nil {
+		t.Fatalf("Failed to create node_modules/@acme: %v", err)
+	}
+	if err := os.Symlink(filepath.Join(tempDir, "packages", "styles"), filepath.Join(tempDir, "node_modules", "@acme", "styles")); err != nil {
+		t.Fatalf("Failed to link workspace package: %v", err)
+	}
+
+	mainPath := filepath.Join(tempDir, "styles", "main.scss")
+	main := []byte(`
+@use 'sass:math';
+@use 'variables';
+@use 'theme' with (
+  $primary: blue
+);
+@forward "mixins" show rem;
+// @use 'commented';
+@import 'a', 'b';
+@use 'tokens' as t;
+@import '~bootstrap/scss/functions';
+@use '~@acme/styles/base';
+`)
+
+	config = Config{StyleLoadPaths: []string{"shared"}}
+	defer func() { config = Config{} }()
+	imports := extractImportsFromContent(mainPath, ".scss", main, tempDir)
+	expected := []string{
+		"styles/_variables.scss",
+		"styles/theme/_index.scss",
+		"styles/_mixins.scss",
+		"styles/_a.scss",
+		"styles/b.css",
+		"shared/_tokens.scss",
+		"packages/styles/_base.scss",
+	}
+	if len(imports) != len(expected) {
+		t.Fatalf("Expected %d imports, got %d: %v", len(expected), len(imports), imports)
+	}
+	for i, path := range expected {
+		want, _ := filepath.EvalSymlinks(filepath.Join(tempDir, path))
+		if got, _ := filepath.EvalSymlinks(imports[i]); got != want {
+			t.Errorf("Import %d: got %s, want %s", i, imports[i], want)
+		}
+	}
+
+	// Less strips import options and adds the .less extension
+	lessPath := filepath.Join(tempDir, "less", "site.less")
+	imports = extractImportsFromContent(lessPath, ".less", []byte(`@import (reference) "mixins";`), tempDir)
+	if len(imports) != 1 || imports[0] != filepath.Join(tempDir, "less", "mixins.less") {
+		t.Errorf("Expected less/mixins.less, got %v", imports)
+	}
+}
@@ -0,0 +1,167 @@
+package main
+
+import (
+	"bytes"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Single-file component formats that mix markup, script and style blocks
+var sfcExtensions = []string{".vue", ".svelte", ".astro"}
+
+// Matches the opening tag of a script or style block
+var sfcBlockPattern = regexp.MustCompile(`(?i)<(script|style)\b((?:[^>"']|"[^"]*"|'[^']*')*?)(/?)>`)
+
+// Matches attributes inside a tag, with or without a value
+var sfcAttributePattern = regexp.MustCompile(`([\w:@.-]+)(?:\s*=\s*(?:"([^"]*)"|'([^']*)'|([^\s"'>]+)))?`)
+
+// Matches the frontmatter fence of an Astro component
+var astroFrontmatterPattern = regexp.MustCompile(`(?s)\A\s*---\r?\n(.*?)\r?\n---`)
+
+// sfcBlock is a script or style section of a component
+type sfcBlock struct {
+	// Extension of the language the block is written in, e.g. .ts or .scss
+	ext     string
+	src     string
+	content []byte
+}
+
+// sfcLanguage splits components into their blocks, each extracted by the language it's written in.
+// Template markup is never scanned, so text that merely looks like an import is ignored.
+type sfcLanguage struct{}
+
+func init() {
+	RegisterLanguage(sfcLanguage{})
+}
+
+// Name identifies the language
+func (sfcLanguage) Name() string {
+	return "single-file components"
+}
+
+// Detect reports whether a file is a Vue, Svelte or Astro component
+func (sfcLanguage) Detect(fileExt string) bool {
+	return containsString(sfcExtensions, fileExt)
+}
+
+// ExtractSpecifiers returns the src attributes of <script src> and <style src> blocks, and the
+// inline blocks along with Astro's frontmatter
+func (sfcLanguage) ExtractSpecifiers(file *SourceFile) []Specifier {
+	var specs []Specifier
+	for _, block := range splitSFCBlocks(file.Ext, file.Content) {
+		if block.src == "" {
+			specs = append(specs, Specifier{Block: &SourceFile{Path: file.Path, Ext: block.ext, Content: block.content, ProjectRoot: file.ProjectRoot}})
+		} else if !isExternalURL(block.src) {
+			specs = append(specs, Specifier{Path: block.src})
+		}
+	}
+	return specs
+}
+
+// IsBuiltin is always false, as src attributes reference local files
+func (sfcLanguage) IsBuiltin(spec Specifier) bool {
+	return false
+}
+
+// ResolveSpecifier resolves a block's src attribute
+func (sfcLanguage) ResolveSpecifier(file *SourceFile, spec Specifier) []string {
+	resolved, err := ResolveImportPath(spec.Path, filepath.Dir(file.Path), file.ProjectRoot)
+	if err != nil {
+		return nil
+	}
+	return []string{resolved}
+}
+
+// splitSFCBlocks returns the script and style blocks of a component, plus Astro's frontmatter
+func splitSFCBlocks(fileExt string, content []byte) []sfcBlock {
+	var blocks []sfcBlock
+
+	// Astro components put their imports in a TypeScript frontmatter block
+	if fileExt == ".astro" {
+		if match := astroFrontmatterPattern.FindSubmatchIndex(content); match != nil {
+			blocks = append(blocks, sfcBlock{ext: ".ts", content: content[match[2]:match[3]]})
+			content = content[match[1]:]
+		}
+	}
+
+	for len(content) > 0 {
+		match := sfcBlockPattern.FindSubmatchIndex(content)
+		if match == nil {
+			break
+		}
+
+		tag := strings.ToLower(string(content[match[2]:match[3]]))
+		attrs := parseTagAttributes(content[match[4]:match[5]])
+		selfClosing := match[7] > match[6]
+
+		block := sfcBlock{src: attrs["src"]}
+		if tag == "script" {
+			block.ext = scriptBlockExtension(fileExt, attrs["lang"])
+		} else {
+			block.ext = styleBlockExtension(attrs["lang"])
+		}
+
+		content = content[match[1]:]
+		if !selfClosing {
+			end := bytes.Index(bytes.ToLower(content), []byte("</"+tag))
+			if end < 0 {
+				end = len(content)
+			}
+			block.content = content[:end]
+			content = content[end:]
+		}
+
+		blocks = append(blocks, block)
+	}
+
+	return blocks
+}
+
+// scriptBlockExtension maps a script lang attribute to the extension of its language
+func scriptBlockExtension(fileExt string, lang string) string {
+	switch strings.ToLower(lang) {
+	case "ts", "typescript":
+		return ".ts"
+	case "tsx":
+		return ".tsx"
+	case "jsx":
+		return ".jsx"
+	case "js", "javascript":
+		return ".js"
+	}
+	// Astro scripts are TypeScript by default
+	if fileExt == ".astro" {
+		return ".ts"
+	}
+	return ".js"
+}
+
+// styleBlockExtension maps a style lang attribute to the extension of its language
+func styleBlockExtension(lang string) string {
+	switch strings.ToLower(lang) {
+	case "scss":
+		return ".scss"
+	case "sass":
+		return ".sass"
+	case "less":
+		return ".less"
+	}
+	return ".css"
+}
+
+// parseTagAttributes returns the attributes of a tag; attributes without a value map to ""
+func parseTagAttributes(attrs []byte) map[string]string {
+	result := make(map[string]string)
+	for _, match := range sfcAttributePattern.FindAllSubmatch(attrs, -1) {
+		name := strings.ToLower(string(match[1]))
+		result[name] = string(match[2]) + string(match[3]) + string(match[4])
+	}
+	return result
+}
+
+// isExternalURL reports whether a reference points outside the project, e.g. a CDN URL or data URI
+func isExternalURL(ref string) bool {
+	lower := strings.ToLower(ref)
+	return strings.HasPrefix(lower, "//") || strings.HasPrefix(lower, "data:") || strings.Contains(lower, "://")
+}
@@ -0,0 +1,156 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestExtractSFCImports tests block-aware extraction for Vue, Svelte and Astro components
+func TestExtractSFCImports(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "sfc-imports-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := []string{
+		"src/components/useCounter.ts",
+		"src/components/vars.scss",
+		"src/components/extra.css",
+		"src/components/store.js",
+		"src/components/Fake.ts",
+		"src/layouts/Base.astro",
+	}
+	for _, path := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(""), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	componentsDir := filepath.Join(tempDir, "src", "components")
+	testCases := []struct {
+		name     string
+		file     string
+		content  string
+		expected []string
+	}{
+		{
+			name: "Vue",
+			file: "Counter.vue",
+			content: `<template>
+  <p>Docs: import Fake from './Fake'</p>
+</template>
+
+<script setup lang="ts">
+import { useCounter } from './useCounter'
+</script>
+
+<style lang="scss" scoped>
+@import './vars.scss';
+</style>
+<style src="./extra.css"></style>
+<script src="https://cdn.example.com/lib.js"></script>
+`,
+			expected: []string{"useCounter.ts", "vars.scss", "extra.css"},
+		},
+		{
+			name: "Svelte",
+			file: "Counter.svelte",
+			content: `<script>
+  import { count } from './store.js';
+</script>
+
+<p>import Fake from './Fake'</p>
+`,
+			expected: []string{"store.js"},
+		},
+		{
+			name: "Astro",
+			file: "Page.astro",
+			content: `---
+import Base from '../layouts/Base.astro';
+---
+<Base><p>import Fake from './Fake'</p></Base>
+<script>
+  import { useCounter } from './useCounter';
+</script>
+`,
+			expected: []string{"Base.astro", "useCounter.ts"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			filePath := filepath.Join(componentsDir, tc.file)
+			imports := extractImportsFromContent(filePath, filepath.Ext(filePath), []byte(tc.content), tempDir)
+
+			if len(imports) != len(tc.expected) {
+				t.Fatalf("Expected %d imports, got %d: %v", len(tc.expected), len(imports), imports)
+			}
+			for i, name := range tc.expected {
+				if filepath.Base(imports[i]) != name {
+					t.Errorf("Import %d: got %s, want %s", i, imports[i], name)
+				}
+			}
+		})
+	}
+}
+
+// TestProcessSFCFiles tests that Svelte and Astro components are collected, as entry files and as imports
+func TestProcessSFCFiles(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "sfc-process-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer resetCollection()
+
+	files := map[string]string{
+		"package.json":     `{"name": "app"}`,
+		"src/Page.vue":     "<script setup>\nimport App from './App.svelte';\n</script>\n",
+		"src/App.svelte":   "<script>\nimport { count } from './store.js';\n</script>\n<p>{$count}</p>\n",
+		"src/store.js":     "export const count = 1;\n",
+		"src/Layout.astro": "---\nimport Header from './Header.astro';\n---\n<Header />\n",
+		"src/Header.astro": "<header></header>\n",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	testCases := []struct {
+		entry    string
+		expected []string
+	}{
+		{"src/Page.vue", []string{"src/Page.vue", "src/App.svelte", "src/store.js"}},
+		{"src/App.svelte", []string{"src/App.svelte", "src/store.js"}},
+		{"src/Layout.astro", []string{"src/Layout.astro", "src/Header.astro"}},
+	}
+
+	for _, tc := range testCases {
+		resetCollection()
+		results := make(map[string]string)
+		if err := ProcessFile(filepath.Join(tempDir, tc.entry), tempDir, results); err != nil {
+			t.Fatalf("%s: ProcessFile failed: %v", tc.entry, err)
+		}
+		if len(results) != len(tc.expected) {
+			t.Errorf("%s: expected %d files, got %d: %v", tc.entry, len(tc.expected), len(results), resultPaths(results))
+		}
+		for _, path := range tc.expected {
+			if _, ok := results[filepath.Join(tempDir, path)]; !ok {
+				t.Errorf("%s: expected %s to be collected", tc.entry, path)
+			}
+		}
+	}
+}
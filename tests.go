@@ -0,0 +1,133 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+)
+
+// Suffixes that mark JavaScript and TypeScript test files, as in foo.test.ts or foo.spec.js
+var jsTestSuffixes = []string{".test", ".spec"}
+
+// IncludeTests adds the tests associated with the collected files, or only with the entry file,
+// along with the files those tests import
+func IncludeTests(entryPath string, projectRoot string, results map[string]string, entryOnly bool) {
+	sources := []string{filepath.Clean(entryPath)}
+	if !entryOnly {
+		sources = nil
+		for path := range results {
+			if _, external := externalFiles[path]; !external {
+				sources = append(sources, path)
+			}
+		}
+		sort.Strings(sources)
+	}
+
+	for _, source := range sources {
+		for _, testPath := range testFilesFor(source, projectRoot) {
+			if err := ProcessFile(testPath, projectRoot, results); err != nil {
+				fmt.Fprintf(os.Stderr, "Warning: could not process test %s: %v\n", testPath, err)
+			}
+		}
+	}
+}
+
+// testFilesFor finds the tests for a source file by the naming conventions of its language
+func testFilesFor(path string, projectRoot string) []string {
+	if isTestFile(path) {
+		return nil
+	}
+
+	dir := filepath.Dir(path)
+	ext := fileExtension(path)
+	name := strings.TrimSuffix(filepath.Base(path), ext)
+
+	var candidates []string
+	switch {
+	case ext == ".go":
+		candidates = append(candidates, filepath.Join(dir, name+"_test.go"))
+
+	case containsString(javaScriptExtensions, ext) && !containsString(declarationExtensions, ext):
+		// Tests may use any JavaScript-like extension, e.g. foo.test.tsx for foo.ts
+		for _, testExt := range []string{".ts", ".tsx", ".js", ".jsx", ".mts", ".cts", ".mjs", ".cjs"} {
+			for _, suffix := range jsTestSuffixes {
+				candidates = append(candidates,
+					filepath.Join(dir, name+suffix+testExt),
+					filepath.Join(dir, "__tests__", name+suffix+testExt))
+			}
+			candidates = append(candidates, filepath.Join(dir, "__tests__", name+testExt))
+		}
+
+	case ext == ".py":
+		candidates = append(candidates,
+			filepath.Join(dir, "test_"+name+".py"),
+			filepath.Join(dir, name+"_test.py"),
+			filepath.Join(dir, "tests", "test_"+name+".py"),
+			filepath.Join(projectRoot, "tests", "test_"+name+".py"))
+
+	case ext == ".java" || ext == ".kt":
+		// Maven and Gradle keep tests in a mirrored src/test tree
+		for _, suffix := range []string{"Test", "Tests"} {
+			candidates = append(candidates, filepath.Join(dir, name+suffix+ext))
+			if mirrored := mirrorPath(dir, "/src/main/", "/src/test/"); mirrored != "" {
+				candidates = append(candidates, filepath.Join(mirrored, name+suffix+ext))
+			}
+		}
+
+	case ext == ".rb":
+		rel, err := filepath.Rel(projectRoot, dir)
+		if err == nil {
+			// app/models/user.rb is tested by spec/models/user_spec.rb or test/models/user_test.rb
+			parts := strings.Split(filepath.ToSlash(rel), "/")
+			if len(parts) > 0 && (parts[0] == "app" || parts[0] == "lib") {
+				parts = parts[1:]
+			}
+			sub := filepath.FromSlash(strings.Join(parts, "/"))
+			candidates = append(candidates,
+				filepath.Join(projectRoot, "spec", sub, name+"_spec.rb"),
+				filepath.Join(projectRoot, "test", sub, name+"_test.rb"))
+		}
+	}
+
+	var tests []string
+	for _, candidate := range candidates {
+		if info, err := statFile(candidate); err == nil && !info.IsDir() && !containsString(tests, candidate) {
+			tests = append(tests, candidate)
+		}
+	}
+	return tests
+}
+
+// isTestFile reports whether a file is itself a test by naming convention
+func isTestFile(path string) bool {
+	base := filepath.Base(path)
+	ext := fileExtension(path)
+	name := strings.TrimSuffix(base, ext)
+
+	switch {
+	case strings.HasSuffix(name, "_test"), strings.HasSuffix(name, "_spec"):
+		return true
+	case ext == ".py" && strings.HasPrefix(name, "test_"):
+		return true
+	case (ext == ".java" || ext == ".kt") && (strings.HasSuffix(name, "Test") || strings.HasSuffix(name, "Tests")):
+		return true
+	}
+	for _, suffix := range jsTestSuffixes {
+		if strings.HasSuffix(name, suffix) {
+			return true
+		}
+	}
+	return strings.Contains(filepath.ToSlash(path), "/__tests__/")
+}
+
+// mirrorPath replaces the last occurrence of from in dir with to, or returns "" if dir doesn't contain it
+func mirrorPath(dir string, from string, to string) string {
+	slashed := filepath.ToSlash(dir) + "/"
+	i := strings.LastIndex(slashed, from)
+	if i < 0 {
+		return ""
+	}
+	return filepath.FromSlash(strings.TrimSuffix(slashed[:i]+to+slashed[i+len(from):], "/"))
+}
@@ -0,0 +1,124 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTestFilesFor tests finding tests by the naming conventions of each language
+func TestTestFilesFor(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "test-files-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := []string{
+		"pkg/parser.go", "pkg/parser_test.go", "pkg/lexer_test.go",
+		"web/src/cart.ts", "web/src/cart.test.ts", "web/src/cart.spec.tsx", "web/src/__tests__/cart.ts",
+		"app/billing.py", "app/test_billing.py", "tests/test_billing.py",
+		"src/main/java/com/acme/Invoice.java", "src/test/java/com/acme/InvoiceTest.java",
+		"app/models/user.rb", "spec/models/user_spec.rb",
+	}
+	for _, path := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(""), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	testCases := map[string][]string{
+		"pkg/parser.go":                       {"pkg/parser_test.go"},
+		"web/src/cart.ts":                     {"web/src/cart.test.ts", "web/src/__tests__/cart.ts", "web/src/cart.spec.tsx"},
+		"app/billing.py":                      {"app/test_billing.py", "tests/test_billing.py"},
+		"src/main/java/com/acme/Invoice.java": {"src/test/java/com/acme/InvoiceTest.java"},
+		"app/models/user.rb":                  {"spec/models/user_spec.rb"},
+		"pkg/parser_test.go":                  nil,
+		"web/src/cart.test.ts":                nil,
+	}
+
+	for source, expected := range testCases {
+		tests := testFilesFor(filepath.Join(tempDir, source), tempDir)
+		if len(tests) != len(expected) {
+			t.Errorf("Expected %d tests for %s, got %v", len(expected), source, tests)
+			continue
+		}
+		for _, path := range expected {
+			if !containsString(tests, filepath.Join(tempDir, path)) {
+				t.Errorf("Expected test %s for %s, got %v", path, source, tests)
+			}
+		}
+	}
+}
+
+// TestIncludeTests tests adding the tests of every collected file or only of the entry file
+func TestIncludeTests(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "include-tests-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"src/app.js":            "import { total } from './cart';",
+		"src/app.test.js":       "import { render } from './testUtils';",
+		"src/testUtils.js":      "",
+		"src/cart.js":           "export const total = 0;",
+		"src/cart.test.js":      "import { total } from './cart';",
+		"src/unrelated.test.js": "",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	testCases := []struct {
+		name      string
+		entryOnly bool
+		expected  []string
+	}{
+		{
+			name:      "All collected files",
+			entryOnly: false,
+			expected:  []string{"src/app.js", "src/cart.js", "src/app.test.js", "src/testUtils.js", "src/cart.test.js"},
+		},
+		{
+			name:      "Entry file only",
+			entryOnly: true,
+			expected:  []string{"src/app.js", "src/cart.js", "src/app.test.js", "src/testUtils.js"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			processedFiles = make(map[string]struct{})
+			entry := filepath.Join(tempDir, "src/app.js")
+
+			results := make(map[string]string)
+			if err := ProcessFile(entry, tempDir, results); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+			IncludeTests(entry, tempDir, results, tc.entryOnly)
+
+			if len(results) != len(tc.expected) {
+				t.Fatalf("Expected %d files, got %d: %v", len(tc.expected), len(results), keys(results))
+			}
+			for _, path := range tc.expected {
+				if _, ok := results[filepath.Join(tempDir, path)]; !ok {
+					t.Errorf("Expected file %s not found in results", path)
+				}
+			}
+		})
+	}
+}
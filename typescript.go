@@ -0,0 +1,238 @@
+package main
+
+import (
+	"encoding/json"
+	"path/filepath"
+	"regexp"
+	"strings"
+)
+
+// Extensions of files that are type checked by TypeScript
+var typeScriptExtensions = []string{".ts", ".tsx", ".mts", ".cts", ".d.ts", ".d.mts", ".d.cts"}
+
+// Declaration file extensions for each JavaScript module extension
+var declarationCompanions = map[string]string{
+	".js":  ".d.ts",
+	".jsx": ".d.ts",
+	".mjs": ".d.mts",
+	".cjs": ".d.cts",
+}
+
+// tsConfig is the subset of tsconfig.json used for finding declarations
+type tsConfig struct {
+	CompilerOptions struct {
+		TypeRoots []string `json:"typeRoots"`
+	} `json:"compilerOptions"`
+}
+
+// Matches trailing commas, which tsconfig.json allows but JSON doesn't
+var trailingCommaPattern = regexp.MustCompile(`,(\s*[}\]])`)
+
+// Local type roots per tsconfig directory
+var tsTypeRoots = make(map[string][]string)
+
+// resolveTypeScriptSource maps a .js specifier in a TypeScript file to the .ts source it refers to,
+// as TypeScript does for ESM-style imports like "./util.js"
+func resolveTypeScriptSource(path string) string {
+	if _, err := statFile(path); err == nil {
+		return path
+	}
+
+	sources := map[string][]string{
+		".js":  {".ts", ".tsx", ".d.ts"},
+		".jsx": {".tsx"},
+		".mjs": {".mts", ".d.mts"},
+		".cjs": {".cts", ".d.cts"},
+	}
+	ext := filepath.Ext(path)
+	for _, sourceExt := range sources[ext] {
+		candidate := strings.TrimSuffix(path, ext) + sourceExt
+		if _, err := statFile(candidate); err == nil {
+			return candidate
+		}
+	}
+	return path
+}
+
+// typeDeclarationsFor returns declaration files that describe an import from a TypeScript file: the
+// .d.ts next to a JavaScript module, declarations for a package in local type roots, and, for packages
+// requested with --types, their bundled or @types declarations
+func typeDeclarationsFor(specifier string, resolved string, fromFile string, projectRoot string) []string {
+	if resolved != "" {
+		ext := fileExtension(resolved)
+		base := strings.TrimSuffix(resolved, ext)
+		jsExts := []string{ext}
+		if ext == "" {
+			// Extensionless imports resolve to whichever JavaScript module exists
+			jsExts = []string{".js", ".jsx", ".mjs", ".cjs"}
+		}
+		for _, jsExt := range jsExts {
+			companionExt, ok := declarationCompanions[jsExt]
+			if !ok {
+				continue
+			}
+			if _, err := statFile(base + jsExt); err != nil {
+				continue
+			}
+			if _, err := statFile(base + companionExt); err == nil {
+				return []string{base + companionExt}
+			}
+		}
+		return nil
+	}
+
+	if !isBareSpecifier(specifier) || strings.HasPrefix(specifier, "#") || strings.HasPrefix(specifier, "node:") {
+		return nil
+	}
+	name, _ := splitPackageSpecifier(specifier)
+
+	var declarations []string
+	for _, root := range typeRootsFor(fromFile, projectRoot) {
+		for _, candidate := range []string{filepath.Join(root, name, "index.d.ts"), filepath.Join(root, name+".d.ts")} {
+			if _, err := statFile(candidate); err == nil {
+				declarations = append(declarations, candidate)
+				break
+			}
+		}
+	}
+
+	if !containsString(config.Types, name) {
+		return declarations
+	}
+
+	// The package's own declarations, then DefinitelyTyped's
+	fromDir := filepath.Dir(fromFile)
+	for _, pkgName := range []string{name, typesPackageName(name)} {
+		pkgDir := findInNodeModules(pkgName, fromDir, projectRoot)
+		if pkgDir == "" {
+			continue
+		}
+		if declaration := packageDeclarations(pkgDir); declaration != "" {
+			declarations = append(declarations, declaration)
+			break
+		}
+	}
+
+	return declarations
+}
+
+// declarationsAllowed reports whether a third-party file is a declaration requested with --types
+func declarationsAllowed(path string, pkg string) bool {
+	if !containsString(declarationExtensions, fileExtension(path)) {
+		return false
+	}
+	for _, name := range config.Types {
+		if pkg == name || pkg == typesPackageName(name) {
+			return true
+		}
+	}
+	return false
+}
+
+// typesPackageName returns the DefinitelyTyped package for a package, e.g. @types/scope__name for @scope/name
+func typesPackageName(name string) string {
+	if strings.HasPrefix(name, "@") {
+		name = strings.Replace(strings.TrimPrefix(name, "@"), "/", "__", 1)
+	}
+	return "@types/" + name
+}
+
+// packageDeclarations returns the entry declaration file of an installed package
+func packageDeclarations(pkgDir string) string {
+	if pkg := readPackageJSON(filepath.Join(pkgDir, "package.json")); pkg != nil {
+		for _, field := range []string{pkg.Types, pkg.Typings} {
+			if field == "" {
+				continue
+			}
+			path := filepath.Join(pkgDir, field)
+			if _, err := statFile(path); err == nil {
+				return path
+			}
+		}
+	}
+
+	indexPath := filepath.Join(pkgDir, "index.d.ts")
+	if _, err := statFile(indexPath); err == nil {
+		return indexPath
+	}
+	return ""
+}
+
+// typeRootsFor returns the project's local type roots: typeRoots from the nearest tsconfig.json
+// outside node_modules, plus the conventional types/ directory
+func typeRootsFor(fromFile string, projectRoot string) []string {
+	dir := filepath.Dir(fromFile)
+	for {
+		if _, err := statFile(filepath.Join(dir, "tsconfig.json")); err == nil {
+			break
+		}
+		if dir == projectRoot || filepath.Dir(dir) == dir {
+			dir = projectRoot
+			break
+		}
+		dir = filepath.Dir(dir)
+	}
+
+	if roots, ok := cacheLoad(tsTypeRoots, dir); ok {
+		return roots
+	}
+
+	var roots []string
+	if content, err := readFile(filepath.Join(dir, "tsconfig.json")); err == nil {
+		var cfg tsConfig
+		if json.Unmarshal(stripJSONComments(content), &cfg) == nil {
+			for _, root := range cfg.CompilerOptions.TypeRoots {
+				path := filepath.Join(dir, root)
+				if !isInsideNodeModules(path) {
+					roots = append(roots, path)
+				}
+			}
+		}
+	}
+	if typesDir := filepath.Join(projectRoot, "types"); !containsString(roots, typesDir) {
+		roots = append(roots, typesDir)
+	}
+
+	cacheStore(tsTypeRoots, dir, roots)
+	return roots
+}
+
+// stripJSONComments removes comments and trailing commas from JSON with comments, as used by tsconfig.json
+func stripJSONComments(content []byte) []byte {
+	var out []byte
+	inString := false
+
+	for i := 0; i < len(content); i++ {
+		c := content[i]
+		switch {
+		case inString:
+			out = append(out, c)
+			if c == '\\' && i+1 < len(content) {
+				i++
+				out = append(out, content[i])
+			} else if c == '"' {
+				inString = false
+			}
+		case c == '"':
+			inString = true
+			out = append(out, c)
+		case c == '/' && i+1 < len(content) && content[i+1] == '/':
+			for i < len(content) && content[i] != '\n' {
+				i++
+			}
+			if i < len(content) {
+				out = append(out, '\n')
+			}
+		case c == '/' && i+1 < len(content) && content[i+1] == '*':
+			end := strings.Index(string(content[i+2:]), "*/")
+			if end < 0 {
+				return out
+			}
+			i += 2 + end + 1
+		default:
+			out = append(out, c)
+		}
+	}
+
+	return trailingCommaPattern.ReplaceAll(out, []byte("$1"))
+}
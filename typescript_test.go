@@ -0,0 +1,112 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"testing"
+)
+
+// TestTypeDeclarations tests including .d.ts companions, local type roots and requested package declarations
+func TestTypeDeclarations(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "type-declarations-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+
+	files := map[string]string{
+		"tsconfig.json":                             "{\n  // Local declarations\n  \"compilerOptions\": {\"typeRoots\": [\"./typings\", \"./node_modules/@types\",]},\n}",
+		"src/app.ts":                                "import { legacy } from './legacy';\nimport { util } from './util.js';\nimport config from 'config-lib';\nimport express from 'express';\nimport left from 'left-pad';\n",
+		"src/legacy.js":                             "export function legacy() {}",
+		"src/legacy.d.ts":                           "export declare function legacy(): void;",
+		"src/util.ts":                               "export const util = 1;",
+		"src/esm.mts":                               "import { util } from './util.js';",
+		"typings/config-lib/index.d.ts":             "declare module 'config-lib';",
+		"types/left-pad.d.ts":                       "declare module 'left-pad';",
+		"node_modules/express/package.json":         `{"name": "express", "main": "index.js"}`,
+		"node_modules/express/index.js":             "",
+		"node_modules/@types/express/package.json":  `{"name": "@types/express", "types": "index.d.ts"}`,
+		"node_modules/@types/express/index.d.ts":    "import { Server } from './server';",
+		"node_modules/@types/express/server.d.ts":   "export interface Server {}",
+		"node_modules/@types/left-pad/index.d.ts":   "",
+		"node_modules/@types/config-lib/index.d.ts": "",
+	}
+	for path, content := range files {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+
+	testCases := []struct {
+		name     string
+		entry    string
+		config   Config
+		expected []string
+	}{
+		{
+			name:     "Companions and local type roots",
+			entry:    "src/app.ts",
+			config:   Config{},
+			expected: []string{"src/app.ts", "src/legacy.js", "src/legacy.d.ts", "src/util.ts", "typings/config-lib/index.d.ts", "types/left-pad.d.ts"},
+		},
+		{
+			name:     "Requested @types package",
+			entry:    "src/app.ts",
+			config:   Config{Types: []string{"express"}},
+			expected: []string{"src/app.ts", "src/legacy.js", "src/legacy.d.ts", "src/util.ts", "typings/config-lib/index.d.ts", "types/left-pad.d.ts", "node_modules/@types/express/index.d.ts", "node_modules/@types/express/server.d.ts"},
+		},
+		{
+			name:     "ES module TypeScript",
+			entry:    "src/esm.mts",
+			config:   Config{},
+			expected: []string{"src/esm.mts", "src/util.ts"},
+		},
+	}
+
+	for _, tc := range testCases {
+		t.Run(tc.name, func(t *testing.T) {
+			config = tc.config
+			defer func() { config = Config{} }()
+			processedFiles = make(map[string]struct{})
+			externalFiles = make(map[string]string)
+			tsTypeRoots = make(map[string][]string)
+
+			results := make(map[string]string)
+			if err := ProcessFile(filepath.Join(tempDir, tc.entry), tempDir, results); err != nil {
+				t.Fatalf("ProcessFile failed: %v", err)
+			}
+
+			if len(results) != len(tc.expected) {
+				t.Fatalf("Expected %d files, got %d: %v", len(tc.expected), len(results), keys(results))
+			}
+			for _, path := range tc.expected {
+				if _, ok := results[filepath.Join(tempDir, path)]; !ok {
+					t.Errorf("Expected file %s not found in results", path)
+				}
+			}
+		})
+	}
+}
+
+// TestFileExtension tests treating declaration extensions as a single extension
+func TestFileExtension(t *testing.T) {
+	testCases := map[string]string{
+		"src/index.d.ts":  ".d.ts",
+		"src/index.d.mts": ".d.mts",
+		"src/mod.cts":     ".cts",
+		"src/app.ts":      ".ts",
+		"src/.d.ts":       ".ts",
+		"src/data.json":   ".json",
+	}
+
+	for path, expected := range testCases {
+		if got := fileExtension(path); got != expected {
+			t.Errorf("fileExtension(%q) = %q, want %q", path, got, expected)
+		}
+	}
+}
@@ -0,0 +1,267 @@
+package main
+
+import (
+	"fmt"
+	"os"
+	"path/filepath"
+	"sort"
+	"strings"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// How long to wait for more changes after one is noticed, so saving several files collects once
+var watchDebounce = 100 * time.Millisecond
+
+// fileChange is how a change can affect the output, from least to most
+type fileChange int
+
+const (
+	noChange fileChange = iota
+	// editedChange is a collected file whose content changed
+	editedChange
+	// movedChange is a file created, removed or renamed where it can change what an import finds
+	movedChange
+	// manifestChange is a config file or manifest that changes how every import below it resolves
+	manifestChange
+)
+
+// watchTargets is what the output depends on: the directories to watch and the changes in them that matter
+type watchTargets struct {
+	dirs map[string]struct{}
+	// files are the collected files
+	files map[string]struct{}
+	// names are paths, without extensions, where creating or removing a file can change what an
+	// import finds, like b for b.ts next to b.js, or a directory on the way to a missing import
+	names map[string]struct{}
+	// lookupDirs are the directories imports were found or looked for in, where any new source file
+	// may be what an import finds, like lib/helper.rb for a require that found nothing before
+	lookupDirs map[string]struct{}
+	// manifestDirs are the directories whose manifests affect the collected files
+	manifestDirs map[string]struct{}
+}
+
+// Watch collects the files again whenever one the output depends on changes, and delivers each new
+// output, until stop is closed. The directories of the collected files, of the manifests above them
+// and where imports were found or looked for are watched with file system notifications. Only the files a
+// change affects are read and parsed again, unless a manifest changed.
+func Watch(projectRoot string, results map[string]string, collect func() (map[string]string, string, error), deliver func(string) error, stop <-chan struct{}) error {
+	watcher, err := fsnotify.NewWatcher()
+	if err != nil {
+		return fmt.Errorf("could not watch files: %v", err)
+	}
+	defer watcher.Close()
+
+	targets := watchedTargets(projectRoot, results)
+	watching := make(map[string]struct{})
+	updateWatches(watcher, watching, targets.dirs)
+	fmt.Fprintf(os.Stderr, "Watching %d directories for changes\n", len(watching))
+
+	changes := make(map[string]fileChange)
+	var settled <-chan time.Time
+	for {
+		select {
+		case <-stop:
+			return nil
+		case err, ok := <-watcher.Errors:
+			if !ok {
+				return nil
+			}
+			fmt.Fprintf(os.Stderr, "Warning: %v\n", err)
+			continue
+		case event, ok := <-watcher.Events:
+			if !ok {
+				return nil
+			}
+			if change := targets.classify(event); change > changes[event.Name] {
+				changes[event.Name] = change
+				settled = time.After(watchDebounce)
+			}
+			continue
+		case <-settled:
+		}
+
+		settled = nil
+		changed := forgetChanges(changes)
+		changes = make(map[string]fileChange)
+		if len(changed) == 1 {
+			fmt.Fprintf(os.Stderr, "%s changed, collecting again\n", changed[0])
+		} else {
+			fmt.Fprintf(os.Stderr, "%s and %d more changed, collecting again\n", changed[0], len(changed)-1)
+		}
+
+		results, output, err := collect()
+		if err != nil {
+			// Keep watching the same files until the problem is fixed
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+			continue
+		}
+		if err := deliver(output); err != nil {
+			fmt.Fprintf(os.Stderr, "Error: %v\n", err)
+		}
+
+		// New imports bring new directories to watch, and removed ones stop being watched
+		targets = watchedTargets(projectRoot, results)
+		updateWatches(watcher, watching, targets.dirs)
+		fmt.Fprintf(os.Stderr, "Collected %d files, watching %d directories for changes\n", len(results), len(watching))
+	}
+}
+
+// forgetChanges drops the loaded files the changes affect, or all of them when a manifest changed,
+// and returns the changed paths in order
+func forgetChanges(changes map[string]fileChange) []string {
+	var changed, edited, moved []string
+	manifest := false
+	for path, change := range changes {
+		changed = append(changed, path)
+		switch change {
+		case editedChange:
+			edited = append(edited, path)
+		case movedChange:
+			moved = append(moved, path)
+		case manifestChange:
+			manifest = true
+		}
+	}
+	sort.Strings(changed)
+
+	if manifest {
+		if loadedFiles != nil {
+			loadedFiles = make(map[fileJob]rememberedFile)
+		}
+	} else {
+		forgetLoadedFiles(edited, moved)
+	}
+	return changed
+}
+
+// updateWatches watches the directories in dirs and stops watching the others
+func updateWatches(watcher *fsnotify.Watcher, watching map[string]struct{}, dirs map[string]struct{}) {
+	for dir := range watching {
+		if _, ok := dirs[dir]; !ok {
+			watcher.Remove(dir)
+			delete(watching, dir)
+		}
+	}
+	for dir := range dirs {
+		if _, ok := watching[dir]; ok {
+			continue
+		}
+		if err := watcher.Add(dir); err != nil {
+			fmt.Fprintf(os.Stderr, "Warning: could not watch %s: %v\n", dir, err)
+			continue
+		}
+		watching[dir] = struct{}{}
+	}
+}
+
+// watchedTargets works out what to watch for the collected files: their directories, the
+// directories where imports were found or looked for, and the directories from each
+// collected file up to the project root, where adding or changing a manifest affects resolution
+func watchedTargets(projectRoot string, results map[string]string) watchTargets {
+	targets := watchTargets{
+		dirs:         make(map[string]struct{}),
+		files:        make(map[string]struct{}),
+		names:        make(map[string]struct{}),
+		lookupDirs:   make(map[string]struct{}),
+		manifestDirs: make(map[string]struct{}),
+	}
+	for path := range results {
+		targets.files[path] = struct{}{}
+		targets.dirs[filepath.Dir(path)] = struct{}{}
+		// A file with the same name and a preferred extension, or a file replacing an index file
+		targets.names[trimExtensions(path)] = struct{}{}
+		targets.names[filepath.Dir(path)] = struct{}{}
+
+		for _, importPath := range importGraph[path] {
+			if _, err := statFile(importPath); err == nil {
+				continue
+			}
+			// The missing import, or a directory on the way to it, is created in the nearest existing directory
+			targets.names[importPath] = struct{}{}
+			targets.names[trimExtensions(importPath)] = struct{}{}
+			targets.watchNearest(filepath.Dir(importPath))
+		}
+		for dir := range importDirectories[path] {
+			targets.lookupDirs[dir] = struct{}{}
+			targets.watchNearest(dir)
+		}
+
+		// Manifests are watched whether or not they exist, so adding one is noticed
+		if !strings.HasPrefix(path, projectRoot+string(filepath.Separator)) {
+			continue
+		}
+		for dir := filepath.Dir(path); ; dir = filepath.Dir(dir) {
+			if _, ok := targets.manifestDirs[dir]; ok {
+				break
+			}
+			targets.manifestDirs[dir] = struct{}{}
+			targets.dirs[dir] = struct{}{}
+			if dir == projectRoot || filepath.Dir(dir) == dir {
+				break
+			}
+		}
+	}
+	return targets
+}
+
+// watchNearest watches dir, or if it doesn't exist, the nearest directory above it that does, where
+// creating the directories on the way to it is noticed
+func (targets watchTargets) watchNearest(dir string) {
+	for ; filepath.Dir(dir) != dir; dir = filepath.Dir(dir) {
+		if _, err := statFile(dir); err == nil {
+			targets.dirs[dir] = struct{}{}
+			return
+		}
+		targets.names[dir] = struct{}{}
+	}
+}
+
+// classify returns how a file system event affects the output. Events on other files, like the
+// output file itself, don't.
+func (targets watchTargets) classify(event fsnotify.Event) fileChange {
+	path := filepath.Clean(event.Name)
+	if event.Op == fsnotify.Chmod {
+		return noChange
+	}
+	if containsString(resolutionManifests, filepath.Base(path)) {
+		if _, ok := targets.manifestDirs[filepath.Dir(path)]; ok {
+			return manifestChange
+		}
+		return noChange
+	}
+
+	moved := event.Op.Has(fsnotify.Create) || event.Op.Has(fsnotify.Remove) || event.Op.Has(fsnotify.Rename)
+	if _, ok := targets.files[path]; ok {
+		if moved {
+			return movedChange
+		}
+		return editedChange
+	}
+	if !moved {
+		return noChange
+	}
+	if _, ok := targets.names[path]; ok {
+		return movedChange
+	}
+	if _, ok := targets.names[trimExtensions(path)]; ok {
+		return movedChange
+	}
+	// Source files, and directories, which have no extension
+	if _, ok := targets.lookupDirs[filepath.Dir(path)]; ok {
+		if ext := fileExtension(path); ext == "" || isSupportedExtension(ext) {
+			return movedChange
+		}
+	}
+	return noChange
+}
+
+// trimExtensions returns a path without the extensions of its file name, like src/b for src/b.d.ts
+func trimExtensions(path string) string {
+	base := filepath.Base(path)
+	if i := strings.Index(base[1:], "."); i >= 0 {
+		return filepath.Join(filepath.Dir(path), base[:i+1])
+	}
+	return path
+}
@@ -0,0 +1,245 @@
+package main
+
+import (
+	"os"
+	"path/filepath"
+	"strings"
+	"testing"
+	"time"
+
+	"github.com/fsnotify/fsnotify"
+)
+
+// TestWatchTargets tests which directories are watched for a collection and which changes in them matter
+func TestWatchTargets(t *testing.T) {
+	// Create a temporary directory
+	tempDir, err := os.MkdirTemp("", "watch-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer resetCollection()
+
+	write := func(path string, content string) {
+		fullPath := filepath.Join(tempDir, path)
+		if err := os.MkdirAll(filepath.Dir(fullPath), 0755); err != nil {
+			t.Fatalf("Failed to create directory for %s: %v", path, err)
+		}
+		if err := os.WriteFile(fullPath, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to create file %s: %v", path, err)
+		}
+	}
+	write("package.json", `{"name": "app"}`)
+	write("src/app.ts", "import { util } from './util';\nimport { missing } from './lib/deep/missing';\n")
+	write("src/util.js", "export const util = 1;\n")
+	write("src/lib/other.ts", "export const other = 1;\n")
+
+	resetCollection()
+	results := make(map[string]string)
+	if err := ProcessFile(filepath.Join(tempDir, "src/app.ts"), tempDir, results); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	targets := watchedTargets(tempDir, results)
+
+	for _, dir := range []string{"", "src", "src/lib"} {
+		if _, ok := targets.dirs[filepath.Join(tempDir, dir)]; !ok {
+			t.Errorf("Expected %q to be watched, got %v", dir, targets.dirs)
+		}
+	}
+
+	tests := []struct {
+		path   string
+		op     fsnotify.Op
+		change fileChange
+	}{
+		{"src/util.js", fsnotify.Write, editedChange},
+		{"src/util.js", fsnotify.Remove, movedChange},
+		{"src/util.js", fsnotify.Chmod, noChange},
+		{"package.json", fsnotify.Write, manifestChange},
+		{"src/tsconfig.json", fsnotify.Create, manifestChange},
+		// A preferred candidate for an import
+		{"src/util.ts", fsnotify.Create, movedChange},
+		// The missing import, or a directory on the way to it
+		{"src/lib/deep", fsnotify.Create, movedChange},
+		{"src/lib/deep/missing.ts", fsnotify.Create, movedChange},
+		// Files nothing depends on
+		{"src/lib/other.ts", fsnotify.Write, noChange},
+		{"src/notes.txt", fsnotify.Create, noChange},
+		{"src/lib/tsconfig.json", fsnotify.Create, noChange},
+	}
+	for _, test := range tests {
+		event := fsnotify.Event{Name: filepath.Join(tempDir, test.path), Op: test.op}
+		if change := targets.classify(event); change != test.change {
+			t.Errorf("%s %s: expected change %d, got %d", test.op, test.path, test.change, change)
+		}
+	}
+}
+
+// TestWatchSearchedDirectories tests that creating a file where an import was looked for without
+// success is noticed, and makes the importing file load again
+func TestWatchSearchedDirectories(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "watch-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer resetCollection()
+	defer func() { loadedFiles = nil }()
+
+	mainPath := filepath.Join(tempDir, "app/main.rb")
+	if err := os.MkdirAll(filepath.Dir(mainPath), 0755); err != nil {
+		t.Fatalf("Failed to create directory: %v", err)
+	}
+	if err := os.WriteFile(mainPath, []byte("require 'helper'\n"), 0644); err != nil {
+		t.Fatalf("Failed to create main.rb: %v", err)
+	}
+
+	resetCollection()
+	loadedFiles = make(map[fileJob]rememberedFile)
+	results := make(map[string]string)
+	if err := ProcessFile(mainPath, tempDir, results); err != nil {
+		t.Fatalf("ProcessFile failed: %v", err)
+	}
+	targets := watchedTargets(tempDir, results)
+
+	// lib doesn't exist yet, so creating it is noticed in the project root
+	for _, path := range []string{"lib", "lib/helper.rb"} {
+		event := fsnotify.Event{Name: filepath.Join(tempDir, path), Op: fsnotify.Create}
+		if change := targets.classify(event); change != movedChange {
+			t.Errorf("Expected creating %s to change the output, got %d", path, change)
+		}
+	}
+
+	forgetLoadedFiles(nil, []string{filepath.Join(tempDir, "lib")})
+	if _, ok := loadedFiles[fileJob{path: mainPath}]; ok {
+		t.Errorf("Expected main.rb to be loaded again once lib is created")
+	}
+}
+
+// TestForgetLoadedFiles tests that a change only drops the loaded files it affects
+func TestForgetLoadedFiles(t *testing.T) {
+	defer func() { loadedFiles = nil }()
+
+	remember := func(path string, imports ...string) {
+		file := loadedFile{job: fileJob{path: path}, path: path, imports: imports}
+		dirs := resolutionDirectories(append([]string{path}, imports...))
+		loadedFiles[file.job] = rememberedFile{loadedFile: file, lookupDirs: dirs}
+	}
+	reset := func() {
+		loadedFiles = make(map[fileJob]rememberedFile)
+		remember("/p/src/app.ts", "/p/src/util.js", "/p/src/lib/page.ts")
+		remember("/p/src/util.js")
+		remember("/p/src/lib/page.ts")
+		remember("/p/other/main.ts", "/p/other/helper.ts")
+		remember("/p/other/helper.ts")
+	}
+
+	tests := []struct {
+		name      string
+		edited    []string
+		moved     []string
+		forgotten []string
+	}{
+		{"edited", []string{"/p/src/util.js"}, nil, []string{"/p/src/util.js"}},
+		{"created next to an import", nil, []string{"/p/src/lib/page.tsx"}, []string{"/p/src/app.ts", "/p/src/lib/page.ts"}},
+		{"created next to files", nil, []string{"/p/other/helper.ts"}, []string{"/p/other/main.ts", "/p/other/helper.ts"}},
+	}
+	for _, test := range tests {
+		reset()
+		forgetLoadedFiles(test.edited, test.moved)
+		for job := range loadedFiles {
+			if containsString(test.forgotten, job.path) {
+				t.Errorf("%s: expected %s to be forgotten", test.name, job.path)
+			}
+		}
+		if len(loadedFiles) != 5-len(test.forgotten) {
+			t.Errorf("%s: expected %d files to be kept, got %d", test.name, 5-len(test.forgotten), len(loadedFiles))
+		}
+	}
+}
+
+// TestWatch tests that the output is collected and delivered again after a change, including new
+// imports, and that unrelated changes are ignored
+func TestWatch(t *testing.T) {
+	tempDir, err := os.MkdirTemp("", "watch-test")
+	if err != nil {
+		t.Fatalf("Failed to create temp directory: %v", err)
+	}
+	defer os.RemoveAll(tempDir)
+	defer resetCollection()
+	defer func() { loadedFiles = nil }()
+	defer func(original time.Duration) { watchDebounce = original }(watchDebounce)
+	watchDebounce = 10 * time.Millisecond
+	loadedFiles = make(map[fileJob]rememberedFile)
+
+	appPath := filepath.Join(tempDir, "app.js")
+	utilPath := filepath.Join(tempDir, "util.js")
+	write := func(path string, content string) {
+		t.Helper()
+		if err := os.WriteFile(path, []byte(content), 0644); err != nil {
+			t.Fatalf("Failed to write %s: %v", path, err)
+		}
+	}
+	write(filepath.Join(tempDir, "package.json"), `{"name": "app"}`)
+	write(utilPath, "export const util = 1;\n")
+	write(appPath, "console.log('app');\n")
+
+	collect := func() (map[string]string, string, error) {
+		resetCollection()
+		results := make(map[string]string)
+		if err := ProcessFile(appPath, tempDir, results); err != nil {
+			return nil, "", err
+		}
+		return results, FormatResults(results), nil
+	}
+	outputs := make(chan string, 10)
+	deliver := func(output string) error {
+		outputs <- output
+		return nil
+	}
+	next := func(what string) string {
+		t.Helper()
+		select {
+		case output := <-outputs:
+			return output
+		case <-time.After(5 * time.Second):
+			t.Fatalf("Expected the output to be delivered again after %s", what)
+		}
+		return ""
+	}
+
+	results, _, err := collect()
+	if err != nil {
+		t.Fatalf("collect failed: %v", err)
+	}
+	stop := make(chan struct{})
+	done := make(chan struct{})
+	go func() {
+		if err := Watch(tempDir, results, collect, deliver, stop); err != nil {
+			t.Errorf("Watch failed: %v", err)
+		}
+		close(done)
+	}()
+	defer func() {
+		close(stop)
+		<-done
+	}()
+
+	// A new import in a watched file is followed, once the watcher has had time to start
+	time.Sleep(50 * time.Millisecond)
+	write(appPath, "import { util } from './util';\nconsole.log(util);\n")
+	if output := next("a new import"); !strings.Contains(output, "util.js") {
+		t.Errorf("Expected the new import to be collected, got:\n%s", output)
+	}
+
+	// Changes nothing depends on aren't collected, so the next output is the edit after them
+	write(filepath.Join(tempDir, "notes.txt"), "notes\n")
+	time.Sleep(50 * time.Millisecond)
+	write(utilPath, "export const util = 2;\n")
+	if output := next("an edit"); !strings.Contains(output, "export const util = 2;") {
+		t.Errorf("Expected the edited file to be read again, got:\n%s", output)
+	}
+	if _, ok := loadedFiles[fileJob{path: appPath}]; !ok {
+		t.Errorf("Expected app.js to be kept when only util.js changed")
+	}
+}